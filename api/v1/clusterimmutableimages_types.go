@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterImmutableImagesSpec defines the desired state of ClusterImmutableImages.
+// The NamespaceSelector selects the namespaces the locks apply to, all of them
+// when it is not set.
+type ClusterImmutableImagesSpec struct {
+	ImmutableImagesSpec `json:",inline"`
+}
+
+// MaxClusterConsumers is the number of consumers kept per lock in the status
+// of a ClusterImmutableImages, every replica of every workload of the selected
+// namespaces would not fit in a single object.
+const MaxClusterConsumers = 10
+
+// NamespaceLockStatus is the outcome of computing the locks of one namespace
+// selected by a ClusterImmutableImages. Consumers of a lock are truncated to
+// MaxClusterConsumers.
+type NamespaceLockStatus struct {
+	// Namespace the locks apply to.
+	Namespace string `json:"namespace"`
+	// MatchedPodCount is the number of pods of the namespace running a listed image.
+	MatchedPodCount int `json:"matchedPodCount,omitempty"`
+
+	LockStatus `json:",inline"`
+}
+
+// ClusterImmutableImagesStatus defines the observed state of ClusterImmutableImages.
+type ClusterImmutableImagesStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the latest observations of the locks.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LockedSecretCount is the number of locked secrets across the selected
+	// namespaces, image pull secrets included.
+	LockedSecretCount int `json:"lockedSecretCount,omitempty"`
+	// MatchedPodCount is the number of pods running a listed image across the
+	// selected namespaces.
+	MatchedPodCount int `json:"matchedPodCount,omitempty"`
+
+	// AuditViolations are the most recent changes to locked objects allowed
+	// because of the Audit enforcement mode.
+	// +optional
+	AuditViolations []AuditViolation `json:"auditViolations,omitempty"`
+
+	// Namespaces lists the locks of every selected namespace running a listed image.
+	// +listType=map
+	// +listMapKey=namespace
+	Namespaces []NamespaceLockStatus `json:"namespaces,omitempty"`
+}
+
+// Namespace returns the locks of the given namespace, if it is selected.
+func (s *ClusterImmutableImagesStatus) Namespace(namespace string) (*NamespaceLockStatus, bool) {
+	for i := range s.Namespaces {
+		if s.Namespaces[i].Namespace == namespace {
+			return &s.Namespaces[i], true
+		}
+	}
+	return nil, false
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Locked",type=integer,JSONPath=`.status.lockedSecretCount`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.matchedPodCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterImmutableImages is the Schema for the clusterimmutableimages API. It
+// locks the secrets of the listed images in every selected namespace.
+type ClusterImmutableImages struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterImmutableImagesSpec   `json:"spec,omitempty"`
+	Status ClusterImmutableImagesStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterImmutableImagesList contains a list of ClusterImmutableImages.
+type ClusterImmutableImagesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImmutableImages `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterImmutableImages{}, &ClusterImmutableImagesList{})
+}
@@ -17,7 +17,11 @@ limitations under the License.
 package v1
 
 import (
+	"slices"
+	"sort"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -28,19 +32,605 @@ type ImmutableImagesSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Image is an example field of ImmutableImages. Edit immutableimages_types.go to remove/update
-	ImageSecretsMap  map[string][]string `json:"imageSecretMap,omitempty"`
-	ImmutableSecrets []string            `json:"immutableSecrets,omitempty"`
+	// Images lists the images whose secrets are locked.
+	// +optional
+	Images []string `json:"images,omitempty"`
+	// ImageSecretsMap is the former way of listing images, its keys are read
+	// as Images and its values are ignored.
+	// Deprecated: list the images in Images instead.
+	// +optional
+	ImageSecretsMap map[string][]string `json:"imageSecretMap,omitempty"`
+	// ImagePatterns select images by wildcard or regular expression in addition
+	// to the exact images of Images.
+	// +optional
+	ImagePatterns []ImagePattern `json:"imagePatterns,omitempty"`
+	// ImageMatchers select images by repository, tag version range and digest
+	// in addition to the exact images of Images.
+	// +optional
+	ImageMatchers []ImageMatcher `json:"imageMatchers,omitempty"`
+	// PodSelector restricts the pods whose secrets are locked to those matching it.
+	// When no image is listed, every container of a selected pod locks its secrets.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// NamespaceSelector restricts the locks to namespaces whose labels match it.
+	// When no image is listed, every container of a selected pod locks its secrets.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// LockExpressions are CEL expressions over the pod, container and reference
+	// variables, a reference only locks its secret when all of them evaluate to
+	// true. When no image is listed, every container for which they hold locks
+	// its secrets. The reference has the secretName, configMapName, container,
+	// containerKind, source and key fields, the name of the other kind of
+	// object is empty. References through a volume also have the volume, mountPath
+	// and readOnly fields. Evaluation is bounded by a cost limit. A reference
+	// on which an expression fails to evaluate, e.g. on a missing field, is
+	// locked and the failure is reported in the status.
+	// +optional
+	LockExpressions []string `json:"lockExpressions,omitempty"`
+	// Exclusions lists the secrets, containers and pods whose references never
+	// lock a secret, even when a listed image consumes it.
+	// +optional
+	Exclusions *Exclusions `json:"exclusions,omitempty"`
+	// EnforcementMode decides what the webhooks do with a change to a secret
+	// or configmap locked by the CR, rules may override it. Defaults to Enforce.
+	// +optional
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+
+	// Rules are named sets of criteria evaluated in addition to the ones
+	// above, a secret is locked when any of them locks it. The name "default"
+	// is reserved for the criteria above when converting to later versions.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Rules []ImageRule `json:"rules,omitempty"`
+
+	// LockImagePullSecrets also locks the registry credentials used by pods running a listed
+	// image, both from the pod's imagePullSecrets and from its ServiceAccount.
+	// +optional
+	LockImagePullSecrets bool `json:"lockImagePullSecrets,omitempty"`
+
+	// Granularity controls whether a locked secret is frozen as a whole or only
+	// the keys consumed by its containers are. Defaults to Secret.
+	// +optional
+	Granularity LockGranularity `json:"granularity,omitempty"`
+
+	// DeletionProtection controls whether a locked secret can be deleted.
+	// Defaults to WhileConsumed.
+	// +optional
+	DeletionProtection DeletionProtection `json:"deletionProtection,omitempty"`
+
+	// BreakGlass lists the requesters allowed past the locks, e.g. to rotate a
+	// leaked credential during an incident. Every exempted change is recorded.
+	// +optional
+	BreakGlass *BreakGlass `json:"breakGlass,omitempty"`
+}
+
+// DefaultRuleName is the name of the rule made of the top level criteria of
+// an ImmutableImagesSpec.
+const DefaultRuleName = "default"
+
+// EnforcementMode is what the webhooks do with a change to a locked secret.
+// +kubebuilder:validation:Enum=Enforce;Warn;Audit
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce rejects changes to locked secrets.
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeWarn allows changes to locked secrets with a warning
+	// returned to the client.
+	EnforcementModeWarn EnforcementMode = "Warn"
+	// EnforcementModeAudit silently allows changes to locked secrets and
+	// records them in the status of the CR.
+	EnforcementModeAudit EnforcementMode = "Audit"
+)
+
+// StrictestEnforcementMode returns the strictest of the modes, an unset mode
+// stands for Enforce.
+func StrictestEnforcementMode(modes ...EnforcementMode) EnforcementMode {
+	if len(modes) == 0 {
+		return EnforcementModeEnforce
+	}
+	strictest := EnforcementModeAudit
+	for _, mode := range modes {
+		switch mode {
+		case EnforcementModeAudit:
+		case EnforcementModeWarn:
+			strictest = EnforcementModeWarn
+		default:
+			return EnforcementModeEnforce
+		}
+	}
+	return strictest
+}
+
+// ImageRule is a named set of criteria selecting the containers whose
+// references lock a secret. Its fields behave like the ones of the same name
+// of ImmutableImagesSpec.
+type ImageRule struct {
+	// Name identifies the rule.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +optional
+	Images []string `json:"images,omitempty"`
+	// +optional
+	ImagePatterns []ImagePattern `json:"imagePatterns,omitempty"`
+	// +optional
+	ImageMatchers []ImageMatcher `json:"imageMatchers,omitempty"`
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// +optional
+	LockExpressions []string `json:"lockExpressions,omitempty"`
+	// +optional
+	Exclusions *Exclusions `json:"exclusions,omitempty"`
+	// EnforcementMode overrides the one of the spec for the objects locked by the rule.
+	// +optional
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// DefaultRule returns the top level criteria of the spec as a rule named
+// DefaultRuleName, and false when none of them is set.
+func (s *ImmutableImagesSpec) DefaultRule() (ImageRule, bool) {
+	rule := ImageRule{
+		Name:              DefaultRuleName,
+		Images:            s.images(),
+		ImagePatterns:     s.ImagePatterns,
+		ImageMatchers:     s.ImageMatchers,
+		PodSelector:       s.PodSelector,
+		NamespaceSelector: s.NamespaceSelector,
+		LockExpressions:   s.LockExpressions,
+		Exclusions:        s.Exclusions,
+	}
+	set := len(rule.Images) > 0 || len(rule.ImagePatterns) > 0 || len(rule.ImageMatchers) > 0 ||
+		rule.PodSelector != nil || rule.NamespaceSelector != nil || len(rule.LockExpressions) > 0 ||
+		rule.Exclusions != nil
+	return rule, set
+}
+
+// images returns Images followed by the keys of the deprecated ImageSecretsMap
+// that it does not list.
+func (s *ImmutableImagesSpec) images() []string {
+	if len(s.ImageSecretsMap) == 0 {
+		return s.Images
+	}
+	images := slices.Clone(s.Images)
+	keys := make([]string, 0, len(s.ImageSecretsMap))
+	for image := range s.ImageSecretsMap {
+		keys = append(keys, image)
+	}
+	sort.Strings(keys)
+	for _, image := range keys {
+		if !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// RuleEnforcementMode returns the enforcement mode of the objects locked by
+// the rule, the one of the spec unless the rule overrides it.
+func (s *ImmutableImagesSpec) RuleEnforcementMode(rule ImageRule) EnforcementMode {
+	if rule.EnforcementMode != "" {
+		return rule.EnforcementMode
+	}
+	return StrictestEnforcementMode(s.EnforcementMode)
+}
+
+// RulesEnforcementMode returns the strictest enforcement mode of the rules of
+// the spec, for the locks that do not record the rules they come from.
+func (s *ImmutableImagesSpec) RulesEnforcementMode() EnforcementMode {
+	rules := s.ImageRules()
+	if len(rules) == 0 {
+		return StrictestEnforcementMode(s.EnforcementMode)
+	}
+	modes := make([]EnforcementMode, 0, len(rules))
+	for _, rule := range rules {
+		modes = append(modes, s.RuleEnforcementMode(rule))
+	}
+	return StrictestEnforcementMode(modes...)
+}
+
+// ImageRules returns the default rule, when set, followed by Rules.
+func (s *ImmutableImagesSpec) ImageRules() []ImageRule {
+	rules := make([]ImageRule, 0, len(s.Rules)+1)
+	if rule, ok := s.DefaultRule(); ok {
+		rules = append(rules, rule)
+	}
+	return append(rules, s.Rules...)
+}
+
+// SetDefaultRule replaces the top level criteria of the spec with the ones of
+// rule, the enforcement mode of the spec applies to every rule and is left as is.
+func (s *ImmutableImagesSpec) SetDefaultRule(rule ImageRule) {
+	s.Images = rule.Images
+	s.ImageSecretsMap = nil
+	s.ImagePatterns = rule.ImagePatterns
+	s.ImageMatchers = rule.ImageMatchers
+	s.PodSelector = rule.PodSelector
+	s.NamespaceSelector = rule.NamespaceSelector
+	s.LockExpressions = rule.LockExpressions
+	s.Exclusions = rule.Exclusions
+}
+
+// ImagePatternType is the syntax of an image pattern.
+// +kubebuilder:validation:Enum=Glob;Regex
+type ImagePatternType string
+
+const (
+	// ImagePatternGlob uses shell wildcards, where "*" does not cross a "/".
+	ImagePatternGlob ImagePatternType = "Glob"
+	// ImagePatternRegex uses a regular expression that must match the whole image.
+	ImagePatternRegex ImagePatternType = "Regex"
+)
+
+// ImagePattern selects every image matching it. Patterns are matched against
+// both the image as written in the pod and its normalized form, e.g.
+// "docker.io/library/nginx:1.25" for "nginx:1.25".
+type ImagePattern struct {
+	// Pattern is the wildcard or regular expression to match images with.
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+	// Type is the syntax of Pattern. Defaults to Glob.
+	// +optional
+	Type ImagePatternType `json:"type,omitempty"`
+}
+
+// NonSemverTagPolicy decides whether a tag that is not a semantic version
+// satisfies a Versions constraint.
+// +kubebuilder:validation:Enum=Ignore;Match
+type NonSemverTagPolicy string
+
+const (
+	// NonSemverTagIgnore never matches tags that are not semantic versions.
+	NonSemverTagIgnore NonSemverTagPolicy = "Ignore"
+	// NonSemverTagMatch matches tags that are not semantic versions, such as
+	// "latest", as if they satisfied the constraint.
+	NonSemverTagMatch NonSemverTagPolicy = "Match"
+)
+
+// ImageMatcher selects the images of a repository, optionally restricted to
+// a tag, a range of versions or a digest. Every field set must match.
+type ImageMatcher struct {
+	// Repository is the image repository, e.g. "nginx" or "registry.internal/auth".
+	// It is normalized like an image, so "nginx" stands for "docker.io/library/nginx".
+	// +kubebuilder:validation:MinLength=1
+	Repository string `json:"repository"`
+	// Tag is the exact tag of the image. Any tag matches when empty.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+	// Versions is a semantic version constraint on the tag, e.g. ">=1.24.0 <1.26.0".
+	// Tags are parsed leniently: a leading "v" and missing minor or patch
+	// versions are accepted. Only major.minor.patch is compared, a variant
+	// suffix such as "-alpine" is ignored, so "1.25-alpine" satisfies
+	// ">=1.24.0 <1.26.0" and "1.26.0-alpine" does not. Pre-releases such as
+	// "1.26.0-rc.1" are compared as their release. Any tag matches when empty.
+	// +optional
+	Versions string `json:"versions,omitempty"`
+	// NonSemverTags decides whether a tag that is not a semantic version satisfies
+	// Versions. Defaults to Ignore.
+	// +optional
+	NonSemverTags NonSemverTagPolicy `json:"nonSemverTags,omitempty"`
+	// Digest pins the image, e.g. "sha256:...". It is matched against the digest
+	// of the image and against the imageID resolved by the container runtime.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+}
+
+// LockGranularity is the unit of a secret that is frozen by a lock.
+// +kubebuilder:validation:Enum=Secret;Key
+type LockGranularity string
+
+const (
+	// LockGranularitySecret rejects any change to a locked secret.
+	LockGranularitySecret LockGranularity = "Secret"
+	// LockGranularityKey only rejects changes to the consumed keys of a locked
+	// secret, unrelated keys can still be added or edited.
+	LockGranularityKey LockGranularity = "Key"
+)
+
+// DeletionProtection is the policy for deleting a locked secret.
+// +kubebuilder:validation:Enum=WhileConsumed;Disabled
+type DeletionProtection string
+
+const (
+	// DeletionProtectionWhileConsumed rejects the deletion of a locked secret
+	// while a running pod holding its lock still consumes it.
+	DeletionProtectionWhileConsumed DeletionProtection = "WhileConsumed"
+	// DeletionProtectionDisabled allows locked secrets to be deleted.
+	DeletionProtectionDisabled DeletionProtection = "Disabled"
+)
+
+// BreakGlass lists the users, groups and service accounts whose changes to
+// locked secrets are allowed.
+type BreakGlass struct {
+	// Users are user names, e.g. "jane@example.com".
+	// +optional
+	Users []string `json:"users,omitempty"`
+	// Groups are group names, e.g. "sre-oncall".
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccounts are service accounts by namespace and name.
+	// +optional
+	ServiceAccounts []ServiceAccountReference `json:"serviceAccounts,omitempty"`
+}
+
+// ServiceAccountReference identifies a service account.
+type ServiceAccountReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ContainerKind identifies the list of the pod spec a container was declared in.
+// +kubebuilder:validation:Enum=Container;InitContainer;EphemeralContainer
+type ContainerKind string
+
+const (
+	ContainerKindContainer          ContainerKind = "Container"
+	ContainerKindInitContainer      ContainerKind = "InitContainer"
+	ContainerKindEphemeralContainer ContainerKind = "EphemeralContainer"
+)
+
+// ReferenceKind is the way a container consumes a secret. Custom reference
+// extractors are free to use kinds of their own.
+type ReferenceKind string
+
+const (
+	ReferenceKindVolume    ReferenceKind = "Volume"
+	ReferenceKindProjected ReferenceKind = "Projected"
+	ReferenceKindEnv       ReferenceKind = "Env"
+	ReferenceKindEnvFrom   ReferenceKind = "EnvFrom"
+	// ReferenceKindImagePullSecret is a registry credential the image of the
+	// container is pulled with.
+	ReferenceKindImagePullSecret ReferenceKind = "ImagePullSecret"
+)
+
+// NamespacedName identifies a locked secret or configmap, locks only apply to
+// the object of that name in that namespace.
+type NamespacedName struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// String returns the namespace/name form of the identity.
+func (n NamespacedName) String() string {
+	return n.Namespace + "/" + n.Name
+}
+
+// SecretLock describes why a secret is part of ImmutableSecrets.
+type SecretLock struct {
+	// Namespace of the locked secret.
+	Namespace string `json:"namespace"`
+	// Name of the locked secret.
+	Name string `json:"name"`
+	// ContainerKinds lists the kinds of containers whose references locked the secret.
+	ContainerKinds []ContainerKind `json:"containerKinds,omitempty"`
+	// Keys lists the keys of the secret consumed by its containers.
+	Keys []string `json:"keys,omitempty"`
+	// AllKeys is set when a container consumes the whole secret, e.g. through
+	// envFrom or a volume without items, so every key is locked.
+	AllKeys bool `json:"allKeys,omitempty"`
+	// Consumers lists every container holding the lock.
+	Consumers []SecretConsumer `json:"consumers,omitempty"`
+	// ConsumerCount is the number of containers holding the lock, set when
+	// Consumers is truncated to keep the status of a cluster CR small.
+	ConsumerCount int `json:"consumerCount,omitempty"`
+	// ConsumerPods lists the names of the pods of every container holding the
+	// lock, set with ConsumerCount so that deletion protection still sees the
+	// pods of the consumers left out of Consumers.
+	ConsumerPods []string `json:"consumerPods,omitempty"`
+	// ContentHash is the SHA-256 fingerprint of the data of the secret, e.g.
+	// "sha256:...", taken when the secret is first locked. At key granularity
+	// only the consumed keys are fingerprinted, unless AllKeys is set. It is
+	// kept while the secret is missing, and a secret of the same name can only
+	// be created again with the same data. Locks that are not enforced follow
+	// the data, and a change exempted by break-glass pins the new data.
+	ContentHash string `json:"contentHash,omitempty"`
+	// ContentMismatch is set when the data of the secret no longer matches
+	// ContentHash, e.g. it was changed while the webhook was unavailable.
+	ContentMismatch bool `json:"contentMismatch,omitempty"`
+	// EnforcementMode is the strictest enforcement mode of the rules locking the secret.
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// ConfigMapLock is the lock on a ConfigMap consumed by a listed image.
+type ConfigMapLock struct {
+	// Namespace of the locked ConfigMap.
+	Namespace string `json:"namespace"`
+	// Name of the locked ConfigMap.
+	Name string `json:"name"`
+	// EnforcementMode is the strictest enforcement mode of the rules locking the ConfigMap.
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// SecretConsumer is a container whose reference to a secret caused it to be locked.
+type SecretConsumer struct {
+	// PodName is the name of the consuming pod, or of the workload when the
+	// reference comes from a pod template.
+	PodName string `json:"podName"`
+	// PodUID is the UID of the consuming pod, or of the workload when the
+	// reference comes from a pod template.
+	PodUID types.UID `json:"podUID,omitempty"`
+	// Container is the name of the consuming container.
+	Container string `json:"container"`
+	// ContainerKind is the list of the pod spec the container was declared in.
+	ContainerKind ContainerKind `json:"containerKind,omitempty"`
+	// Image is the image of the consuming container.
+	Image string `json:"image"`
+	// Reference is the way the container consumes the secret.
+	Reference ReferenceKind `json:"reference"`
+}
+
+// Exclusions are escape hatches for references that must never lock a secret,
+// e.g. TLS secrets renewed by cert-manager. Name patterns are globs. The
+// container and pod exclusions apply to configmaps as well.
+type Exclusions struct {
+	// SecretNames excludes the secrets whose name matches one of the patterns.
+	// +optional
+	SecretNames []string `json:"secretNames,omitempty"`
+	// SecretSelector excludes the secrets whose labels match it.
+	// +optional
+	SecretSelector *metav1.LabelSelector `json:"secretSelector,omitempty"`
+	// ContainerNames excludes the containers whose name matches one of the patterns.
+	// +optional
+	ContainerNames []string `json:"containerNames,omitempty"`
+	// PodSelector excludes the pods whose labels match it.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// ExclusionReason is the rule of Exclusions that skipped a reference.
+type ExclusionReason string
+
+const (
+	ExclusionReasonSecretName    ExclusionReason = "SecretName"
+	ExclusionReasonSecretLabels  ExclusionReason = "SecretLabels"
+	ExclusionReasonContainerName ExclusionReason = "ContainerName"
+	ExclusionReasonPodLabels     ExclusionReason = "PodLabels"
+)
+
+// SkippedReference is a reference to a secret that was not locked because of Exclusions.
+type SkippedReference struct {
+	// Secret is the name of the referenced secret.
+	Secret string `json:"secret"`
+	// PodName is the name of the referencing pod, or of the workload when the
+	// reference comes from a pod template.
+	PodName string `json:"podName"`
+	// Container is the name of the referencing container.
+	Container string `json:"container"`
+	// Reference is the way the container consumes the secret.
+	Reference ReferenceKind `json:"reference"`
+	// Reason is the rule of Exclusions that skipped the reference.
+	Reason ExclusionReason `json:"reason"`
+}
+
+// MaxAuditViolations is the number of most recent audit violations kept in
+// the status of a CR.
+const MaxAuditViolations = 20
+
+// AuditViolation is a change to a locked object that was allowed because of
+// the Audit enforcement mode.
+type AuditViolation struct {
+	// Time is when the change was admitted.
+	Time metav1.Time `json:"time"`
+	// Operation is the admission operation, e.g. UPDATE.
+	Operation string `json:"operation,omitempty"`
+	// Kind is the kind of the locked object, Secret or ConfigMap.
+	Kind string `json:"kind"`
+	// Namespace of the locked object.
+	Namespace string `json:"namespace"`
+	// Name of the locked object.
+	Name string `json:"name"`
+	// User is the name of the requester.
+	User string `json:"user,omitempty"`
+	// Message is the reason the change would have been rejected.
+	Message string `json:"message"`
+}
+
+// AppendAuditViolation appends the violation, only keeping the
+// MaxAuditViolations most recent ones.
+func AppendAuditViolation(violations []AuditViolation, violation AuditViolation) []AuditViolation {
+	violations = append(violations, violation)
+	if len(violations) > MaxAuditViolations {
+		violations = violations[len(violations)-MaxAuditViolations:]
+	}
+	return violations
+}
+
+// Condition types of ImmutableImages.
+const (
+	// ConditionReady is true when the locks are up to date and the webhooks are served.
+	ConditionReady = "Ready"
+	// ConditionEnforcing is true when the webhooks reject updates to locked
+	// secrets, false when they are disabled or when no rule is in Enforce mode.
+	ConditionEnforcing = "Enforcing"
+	// ConditionDegraded is true when the last reconcile failed, the locks
+	// computed before it are kept, when a locked secret no longer matches
+	// its pinned content, or when a lock expression failed to evaluate.
+	ConditionDegraded = "Degraded"
+)
+
+// Reasons of the conditions of ImmutableImages.
+const (
+	ReasonReconciled          = "Reconciled"
+	ReasonWebhookEnabled      = "WebhookEnabled"
+	ReasonWebhookDisabled     = "WebhookDisabled"
+	ReasonGetNamespaceFailed  = "GetNamespaceFailed"
+	ReasonListPodsFailed      = "ListPodsFailed"
+	ReasonListWorkloadsFailed = "ListWorkloadsFailed"
+	ReasonFetchSecretsFailed  = "FetchSecretsFailed"
+	ReasonContentMismatch     = "ContentMismatch"
+	ReasonExpressionError     = "ExpressionError"
+	ReasonWarnOnly            = "WarnOnly"
+	ReasonAuditOnly           = "AuditOnly"
+)
+
+// LockStatus is the outcome of computing the locks of a namespace.
+type LockStatus struct {
+	// ImageSecretsMap lists, for every image of Images, the secrets it locks.
+	ImageSecretsMap map[string][]string `json:"imageSecretMap,omitempty"`
+	// ImmutableSecrets lists the secrets the webhook refuses to update.
+	ImmutableSecrets []NamespacedName `json:"immutableSecrets,omitempty"`
+	// LockedSecrets records, for every secret in ImmutableSecrets, what caused it to be locked.
+	LockedSecrets []SecretLock `json:"lockedSecrets,omitempty"`
+	// SkippedReferences lists the references of listed images left unlocked
+	// because of Exclusions, with the rule that excluded them.
+	SkippedReferences []SkippedReference `json:"skippedReferences,omitempty"`
+	// MatchedImages lists, for every pattern of ImagePatterns and every matcher
+	// of ImageMatchers, the concrete images it matched.
+	MatchedImages map[string][]string `json:"matchedImages,omitempty"`
+	// NonSemverTags lists the images seen by a matcher with a Versions constraint
+	// whose tag is not a semantic version.
+	NonSemverTags []string `json:"nonSemverTags,omitempty"`
+	// ImmutablePullSecrets lists the image pull secrets locked because of LockImagePullSecrets.
+	ImmutablePullSecrets []NamespacedName `json:"immutablePullSecrets,omitempty"`
+	// LockedPullSecrets records, for every secret in ImmutablePullSecrets, the
+	// containers pulling with it and its pinned content.
+	LockedPullSecrets []SecretLock `json:"lockedPullSecrets,omitempty"`
+	// ImmutableConfigMaps is the ConfigMap counterpart of ImmutableSecrets.
+	ImmutableConfigMaps []NamespacedName `json:"immutableConfigMaps,omitempty"`
+	// LockedConfigMaps records, for every ConfigMap in ImmutableConfigMaps,
+	// the enforcement mode of its lock.
+	LockedConfigMaps []ConfigMapLock `json:"lockedConfigMaps,omitempty"`
+	// ExpressionErrors lists the lock expressions that failed to evaluate,
+	// with their error. The references they failed on are locked.
+	ExpressionErrors []string `json:"expressionErrors,omitempty"`
 }
 
 // ImmutableImagesStatus defines the observed state of ImmutableImages.
 type ImmutableImagesStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the generation of the spec the status was computed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the latest observations of the locks.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LockedSecretCount is the number of locked secrets, image pull secrets included.
+	LockedSecretCount int `json:"lockedSecretCount,omitempty"`
+	// MatchedPodCount is the number of pods running a listed image.
+	MatchedPodCount int `json:"matchedPodCount,omitempty"`
+
+	// AuditViolations are the most recent changes to locked objects allowed
+	// because of the Audit enforcement mode.
+	// +optional
+	AuditViolations []AuditViolation `json:"auditViolations,omitempty"`
+
+	LockStatus `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Locked",type=integer,JSONPath=`.status.lockedSecretCount`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.matchedPodCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ImmutableImages is the Schema for the immutableimages API.
 type ImmutableImages struct {
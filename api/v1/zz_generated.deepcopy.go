@@ -21,16 +21,304 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditViolation) DeepCopyInto(out *AuditViolation) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditViolation.
+func (in *AuditViolation) DeepCopy() *AuditViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditViolation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BreakGlass) DeepCopyInto(out *BreakGlass) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]ServiceAccountReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BreakGlass.
+func (in *BreakGlass) DeepCopy() *BreakGlass {
+	if in == nil {
+		return nil
+	}
+	out := new(BreakGlass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImmutableImages) DeepCopyInto(out *ClusterImmutableImages) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImmutableImages.
+func (in *ClusterImmutableImages) DeepCopy() *ClusterImmutableImages {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImmutableImages)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImmutableImages) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImmutableImagesList) DeepCopyInto(out *ClusterImmutableImagesList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterImmutableImages, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImmutableImagesList.
+func (in *ClusterImmutableImagesList) DeepCopy() *ClusterImmutableImagesList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImmutableImagesList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImmutableImagesList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImmutableImagesSpec) DeepCopyInto(out *ClusterImmutableImagesSpec) {
+	*out = *in
+	in.ImmutableImagesSpec.DeepCopyInto(&out.ImmutableImagesSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImmutableImagesSpec.
+func (in *ClusterImmutableImagesSpec) DeepCopy() *ClusterImmutableImagesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImmutableImagesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImmutableImagesStatus) DeepCopyInto(out *ClusterImmutableImagesStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AuditViolations != nil {
+		in, out := &in.AuditViolations, &out.AuditViolations
+		*out = make([]AuditViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceLockStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImmutableImagesStatus.
+func (in *ClusterImmutableImagesStatus) DeepCopy() *ClusterImmutableImagesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImmutableImagesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapLock) DeepCopyInto(out *ConfigMapLock) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapLock.
+func (in *ConfigMapLock) DeepCopy() *ConfigMapLock {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exclusions) DeepCopyInto(out *Exclusions) {
+	*out = *in
+	if in.SecretNames != nil {
+		in, out := &in.SecretNames, &out.SecretNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretSelector != nil {
+		in, out := &in.SecretSelector, &out.SecretSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerNames != nil {
+		in, out := &in.ContainerNames, &out.ContainerNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exclusions.
+func (in *Exclusions) DeepCopy() *Exclusions {
+	if in == nil {
+		return nil
+	}
+	out := new(Exclusions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageMatcher) DeepCopyInto(out *ImageMatcher) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMatcher.
+func (in *ImageMatcher) DeepCopy() *ImageMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePattern) DeepCopyInto(out *ImagePattern) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePattern.
+func (in *ImagePattern) DeepCopy() *ImagePattern {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePattern)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRule) DeepCopyInto(out *ImageRule) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePatterns != nil {
+		in, out := &in.ImagePatterns, &out.ImagePatterns
+		*out = make([]ImagePattern, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageMatchers != nil {
+		in, out := &in.ImageMatchers, &out.ImageMatchers
+		*out = make([]ImageMatcher, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LockExpressions != nil {
+		in, out := &in.LockExpressions, &out.LockExpressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclusions != nil {
+		in, out := &in.Exclusions, &out.Exclusions
+		*out = new(Exclusions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRule.
+func (in *ImageRule) DeepCopy() *ImageRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImmutableImages) DeepCopyInto(out *ImmutableImages) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImmutableImages.
@@ -86,6 +374,11 @@ func (in *ImmutableImagesList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImmutableImagesSpec) DeepCopyInto(out *ImmutableImagesSpec) {
 	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ImageSecretsMap != nil {
 		in, out := &in.ImageSecretsMap, &out.ImageSecretsMap
 		*out = make(map[string][]string, len(*in))
@@ -102,11 +395,48 @@ func (in *ImmutableImagesSpec) DeepCopyInto(out *ImmutableImagesSpec) {
 			(*out)[key] = outVal
 		}
 	}
-	if in.ImmutableSecrets != nil {
-		in, out := &in.ImmutableSecrets, &out.ImmutableSecrets
+	if in.ImagePatterns != nil {
+		in, out := &in.ImagePatterns, &out.ImagePatterns
+		*out = make([]ImagePattern, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageMatchers != nil {
+		in, out := &in.ImageMatchers, &out.ImageMatchers
+		*out = make([]ImageMatcher, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LockExpressions != nil {
+		in, out := &in.LockExpressions, &out.LockExpressions
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Exclusions != nil {
+		in, out := &in.Exclusions, &out.Exclusions
+		*out = new(Exclusions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ImageRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BreakGlass != nil {
+		in, out := &in.BreakGlass, &out.BreakGlass
+		*out = new(BreakGlass)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImmutableImagesSpec.
@@ -122,6 +452,21 @@ func (in *ImmutableImagesSpec) DeepCopy() *ImmutableImagesSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImmutableImagesStatus) DeepCopyInto(out *ImmutableImagesStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AuditViolations != nil {
+		in, out := &in.AuditViolations, &out.AuditViolations
+		*out = make([]AuditViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LockStatus.DeepCopyInto(&out.LockStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImmutableImagesStatus.
@@ -133,3 +478,210 @@ func (in *ImmutableImagesStatus) DeepCopy() *ImmutableImagesStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LockStatus) DeepCopyInto(out *LockStatus) {
+	*out = *in
+	if in.ImageSecretsMap != nil {
+		in, out := &in.ImageSecretsMap, &out.ImageSecretsMap
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.ImmutableSecrets != nil {
+		in, out := &in.ImmutableSecrets, &out.ImmutableSecrets
+		*out = make([]NamespacedName, len(*in))
+		copy(*out, *in)
+	}
+	if in.LockedSecrets != nil {
+		in, out := &in.LockedSecrets, &out.LockedSecrets
+		*out = make([]SecretLock, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SkippedReferences != nil {
+		in, out := &in.SkippedReferences, &out.SkippedReferences
+		*out = make([]SkippedReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchedImages != nil {
+		in, out := &in.MatchedImages, &out.MatchedImages
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.NonSemverTags != nil {
+		in, out := &in.NonSemverTags, &out.NonSemverTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImmutablePullSecrets != nil {
+		in, out := &in.ImmutablePullSecrets, &out.ImmutablePullSecrets
+		*out = make([]NamespacedName, len(*in))
+		copy(*out, *in)
+	}
+	if in.LockedPullSecrets != nil {
+		in, out := &in.LockedPullSecrets, &out.LockedPullSecrets
+		*out = make([]SecretLock, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImmutableConfigMaps != nil {
+		in, out := &in.ImmutableConfigMaps, &out.ImmutableConfigMaps
+		*out = make([]NamespacedName, len(*in))
+		copy(*out, *in)
+	}
+	if in.LockedConfigMaps != nil {
+		in, out := &in.LockedConfigMaps, &out.LockedConfigMaps
+		*out = make([]ConfigMapLock, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpressionErrors != nil {
+		in, out := &in.ExpressionErrors, &out.ExpressionErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LockStatus.
+func (in *LockStatus) DeepCopy() *LockStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LockStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceLockStatus) DeepCopyInto(out *NamespaceLockStatus) {
+	*out = *in
+	in.LockStatus.DeepCopyInto(&out.LockStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceLockStatus.
+func (in *NamespaceLockStatus) DeepCopy() *NamespaceLockStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceLockStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedName) DeepCopyInto(out *NamespacedName) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedName.
+func (in *NamespacedName) DeepCopy() *NamespacedName {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedName)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretConsumer) DeepCopyInto(out *SecretConsumer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretConsumer.
+func (in *SecretConsumer) DeepCopy() *SecretConsumer {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretConsumer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretLock) DeepCopyInto(out *SecretLock) {
+	*out = *in
+	if in.ContainerKinds != nil {
+		in, out := &in.ContainerKinds, &out.ContainerKinds
+		*out = make([]ContainerKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Consumers != nil {
+		in, out := &in.Consumers, &out.Consumers
+		*out = make([]SecretConsumer, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumerPods != nil {
+		in, out := &in.ConsumerPods, &out.ConsumerPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretLock.
+func (in *SecretLock) DeepCopy() *SecretLock {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretLock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountReference) DeepCopyInto(out *ServiceAccountReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountReference.
+func (in *ServiceAccountReference) DeepCopy() *ServiceAccountReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkippedReference) DeepCopyInto(out *SkippedReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkippedReference.
+func (in *SkippedReference) DeepCopy() *SkippedReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SkippedReference)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+)
+
+// ConvertTo converts this ImmutableImages to the Hub version (v1). The rule
+// named "default" becomes the top level criteria of v1.
+func (src *ImmutableImages) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*batchv1.ImmutableImages)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = batchv1.ImmutableImagesSpec{
+		LockImagePullSecrets: src.Spec.LockImagePullSecrets,
+		Granularity:          src.Spec.Granularity,
+		DeletionProtection:   src.Spec.DeletionProtection,
+		BreakGlass:           src.Spec.BreakGlass,
+		EnforcementMode:      src.Spec.EnforcementMode,
+	}
+	var defaultMode batchv1.EnforcementMode
+	for _, rule := range src.Spec.Rules {
+		hubRule := batchv1.ImageRule{
+			Name:              rule.Name,
+			Images:            rule.Match.Images,
+			ImagePatterns:     rule.Match.Patterns,
+			ImageMatchers:     rule.Match.Matchers,
+			PodSelector:       rule.PodSelector,
+			NamespaceSelector: rule.NamespaceSelector,
+			LockExpressions:   rule.LockExpressions,
+			Exclusions:        rule.Exclusions,
+			EnforcementMode:   rule.EnforcementMode,
+		}
+		if rule.Name == batchv1.DefaultRuleName {
+			// v1 has no place for a default rule without criteria, it locks
+			// nothing and only its mode is kept
+			if hasCriteria(rule) {
+				dst.Spec.SetDefaultRule(hubRule)
+			}
+			defaultMode = rule.EnforcementMode
+			continue
+		}
+		dst.Spec.Rules = append(dst.Spec.Rules, hubRule)
+	}
+	// The mode of the spec applies to the top level criteria of v1, so the
+	// mode of a default rule becomes the one of the spec and the mode of v2
+	// moves to the other rules
+	if defaultMode != "" && defaultMode != src.Spec.EnforcementMode {
+		for i := range dst.Spec.Rules {
+			if dst.Spec.Rules[i].EnforcementMode == "" {
+				dst.Spec.Rules[i].EnforcementMode = batchv1.StrictestEnforcementMode(src.Spec.EnforcementMode)
+			}
+		}
+		dst.Spec.EnforcementMode = defaultMode
+	}
+
+	dst.Status = batchv1.ImmutableImagesStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		LockedSecretCount:  src.Status.LockedSecretCount,
+		MatchedPodCount:    src.Status.MatchedPodCount,
+		AuditViolations:    src.Status.AuditViolations,
+		LockStatus:         src.Status.Locks,
+	}
+	return nil
+}
+
+// hasCriteria reports whether the rule sets anything but its name and mode.
+func hasCriteria(rule Rule) bool {
+	return len(rule.Match.Images) > 0 || len(rule.Match.Patterns) > 0 || len(rule.Match.Matchers) > 0 ||
+		rule.PodSelector != nil || rule.NamespaceSelector != nil || len(rule.LockExpressions) > 0 ||
+		rule.Exclusions != nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version. The top
+// level criteria of v1, when set, become the first rule, named "default".
+func (dst *ImmutableImages) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*batchv1.ImmutableImages)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = ImmutableImagesSpec{
+		LockImagePullSecrets: src.Spec.LockImagePullSecrets,
+		Granularity:          src.Spec.Granularity,
+		DeletionProtection:   src.Spec.DeletionProtection,
+		BreakGlass:           src.Spec.BreakGlass,
+		EnforcementMode:      src.Spec.EnforcementMode,
+	}
+	for _, hubRule := range src.Spec.ImageRules() {
+		dst.Spec.Rules = append(dst.Spec.Rules, Rule{
+			Name: hubRule.Name,
+			Match: ImageMatch{
+				Images:   hubRule.Images,
+				Patterns: hubRule.ImagePatterns,
+				Matchers: hubRule.ImageMatchers,
+			},
+			PodSelector:       hubRule.PodSelector,
+			NamespaceSelector: hubRule.NamespaceSelector,
+			LockExpressions:   hubRule.LockExpressions,
+			Exclusions:        hubRule.Exclusions,
+			EnforcementMode:   hubRule.EnforcementMode,
+		})
+	}
+
+	dst.Status = ImmutableImagesStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		LockedSecretCount:  src.Status.LockedSecretCount,
+		MatchedPodCount:    src.Status.MatchedPodCount,
+		AuditViolations:    src.Status.AuditViolations,
+		Locks:              src.Status.LockStatus,
+	}
+	return nil
+}
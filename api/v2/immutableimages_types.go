@@ -0,0 +1,158 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+)
+
+// ImmutableImagesSpec defines the desired state of ImmutableImages.
+type ImmutableImagesSpec struct {
+	// Rules are the named sets of criteria selecting the containers whose
+	// references lock a secret, a secret is locked when any of them locks it.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Rules []Rule `json:"rules,omitempty"`
+
+	// EnforcementMode decides what the webhooks do with a change to a secret
+	// or configmap locked by a rule, rules may override it. Defaults to Enforce.
+	// +optional
+	EnforcementMode batchv1.EnforcementMode `json:"enforcementMode,omitempty"`
+
+	// LockImagePullSecrets also locks the registry credentials used by pods matched by a rule,
+	// both from the pod's imagePullSecrets and from its ServiceAccount.
+	// +optional
+	LockImagePullSecrets bool `json:"lockImagePullSecrets,omitempty"`
+
+	// Granularity controls whether a locked secret is frozen as a whole or only
+	// the keys consumed by its containers are. Defaults to Secret.
+	// +optional
+	Granularity batchv1.LockGranularity `json:"granularity,omitempty"`
+
+	// DeletionProtection controls whether a locked secret can be deleted.
+	// Defaults to WhileConsumed.
+	// +optional
+	DeletionProtection batchv1.DeletionProtection `json:"deletionProtection,omitempty"`
+
+	// BreakGlass lists the requesters allowed past the locks, e.g. to rotate a
+	// leaked credential during an incident. Every exempted change is recorded.
+	// +optional
+	BreakGlass *batchv1.BreakGlass `json:"breakGlass,omitempty"`
+}
+
+// Rule is a named set of criteria selecting the containers whose references
+// lock a secret. Every criterion set must hold.
+type Rule struct {
+	// Name identifies the rule.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Match selects the images of the containers.
+	// +optional
+	Match ImageMatch `json:"match,omitempty"`
+	// PodSelector restricts the rule to the pods matching it.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// NamespaceSelector restricts the rule to namespaces whose labels match it.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// LockExpressions are CEL expressions over the pod, container and reference
+	// variables, a reference only locks its secret when all of them evaluate to true.
+	// The reference has the secretName, configMapName, container, containerKind,
+	// source and key fields, plus volume, mountPath and readOnly when consumed
+	// through a volume. A reference on which an expression fails to evaluate is
+	// locked and the failure is reported in the status.
+	// +optional
+	LockExpressions []string `json:"lockExpressions,omitempty"`
+	// Exclusions lists the secrets, containers and pods whose references never
+	// lock a secret through this rule.
+	// +optional
+	Exclusions *batchv1.Exclusions `json:"exclusions,omitempty"`
+	// EnforcementMode overrides the one of the spec for the objects locked by the rule.
+	// +optional
+	EnforcementMode batchv1.EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// ImageMatch selects images by name, pattern or matcher. When empty, every
+// container matches the rule as long as it has a podSelector, namespaceSelector
+// or lockExpressions to narrow it down, a rule with none of them locks nothing.
+type ImageMatch struct {
+	// Images lists exact images.
+	// +optional
+	Images []string `json:"images,omitempty"`
+	// Patterns select images by wildcard or regular expression.
+	// +optional
+	Patterns []batchv1.ImagePattern `json:"patterns,omitempty"`
+	// Matchers select images by repository, tag version range and digest.
+	// +optional
+	Matchers []batchv1.ImageMatcher `json:"matchers,omitempty"`
+}
+
+// ImmutableImagesStatus defines the observed state of ImmutableImages.
+type ImmutableImagesStatus struct {
+	// ObservedGeneration is the generation of the spec the status was computed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the latest observations of the locks.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LockedSecretCount is the number of locked secrets, image pull secrets included.
+	LockedSecretCount int `json:"lockedSecretCount,omitempty"`
+	// MatchedPodCount is the number of pods matched by a rule.
+	MatchedPodCount int `json:"matchedPodCount,omitempty"`
+
+	// AuditViolations are the most recent changes to locked objects allowed
+	// because of the Audit enforcement mode.
+	// +optional
+	AuditViolations []batchv1.AuditViolation `json:"auditViolations,omitempty"`
+
+	// Locks are the locks computed from the rules.
+	// +optional
+	Locks batchv1.LockStatus `json:"locks,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Locked",type=integer,JSONPath=`.status.lockedSecretCount`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.matchedPodCount`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImmutableImages is the Schema for the immutableimages API.
+type ImmutableImages struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImmutableImagesSpec   `json:"spec,omitempty"`
+	Status ImmutableImagesStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ImmutableImagesList contains a list of ImmutableImages.
+type ImmutableImagesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImmutableImages `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImmutableImages{}, &ImmutableImagesList{})
+}
@@ -36,8 +36,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	batchv2 "github.com/brongulus/secret-controller/api/v2"
 	"github.com/brongulus/secret-controller/internal/controller"
 	webhookcorev1 "github.com/brongulus/secret-controller/internal/webhook/v1"
+	"github.com/brongulus/secret-controller/pkg/extractor"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -50,6 +52,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(batchv2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -146,19 +149,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Secret references are found by the built-in extractors, append extractors
+	// of your own here to lock secrets consumed through in-house conventions
+	extractors := extractor.Defaults()
 	if err = (&controller.ImmutableImagesReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Extractors:       extractors,
+		WebhooksDisabled: os.Getenv("ENABLE_WEBHOOKS") == "false",
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImmutableImages")
 		os.Exit(1)
 	}
+	if err = (&controller.ClusterImmutableImagesReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Extractors:       extractors,
+		WebhooksDisabled: os.Getenv("ENABLE_WEBHOOKS") == "false",
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterImmutableImages")
+		os.Exit(1)
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err = webhookcorev1.SetupSecretWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Secret")
 			os.Exit(1)
 		}
+		if err = webhookcorev1.SetupConfigMapWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ConfigMap")
+			os.Exit(1)
+		}
+		// Also serves the /convert endpoint converting ImmutableImages between
+		// v1, the storage version, and v2
+		if err = webhookcorev1.SetupImmutableImagesWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ImmutableImages")
+			os.Exit(1)
+		}
+		if err = webhookcorev1.SetupClusterImmutableImagesWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterImmutableImages")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
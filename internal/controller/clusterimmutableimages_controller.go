@@ -0,0 +1,305 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+	appsv1 "k8s.io/api/apps/v1"
+	kbatchv1 "k8s.io/api/batch/v1"
+)
+
+// ClusterImmutableImagesReconciler reconciles a ClusterImmutableImages object
+type ClusterImmutableImagesReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Extractors find the secrets referenced by a pod, extractor.Defaults()
+	// are used when nil.
+	Extractors []extractor.SecretReferenceExtractor
+
+	// WebhooksDisabled is set when the manager does not serve the validating
+	// webhooks, so that the CRs report that their locks are not enforced.
+	WebhooksDisabled bool
+}
+
+// +kubebuilder:rbac:groups=batch.github.com,resources=clusterimmutableimages,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch.github.com,resources=clusterimmutableimages/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch.github.com,resources=clusterimmutableimages/finalizers,verbs=update
+
+// namespaced returns the reconciler of ImmutableImages the locks of every
+// selected namespace are computed with
+func (r *ClusterImmutableImagesReconciler) namespaced() *ImmutableImagesReconciler {
+	return &ImmutableImagesReconciler{
+		Client:           r.Client,
+		Scheme:           r.Scheme,
+		Extractors:       r.Extractors,
+		WebhooksDisabled: r.WebhooksDisabled,
+	}
+}
+
+// Reconcile computes the locks of the ClusterImmutableImages in every namespace
+// matching its namespaceSelector, as an ImmutableImages living there would.
+// Requests carrying a namespace come from an event in that namespace and only
+// recompute its locks.
+func (r *ClusterImmutableImagesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	images := &batchv1.ClusterImmutableImages{}
+	if err := r.Get(ctx, types.NamespacedName{Name: req.Name}, images); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "Could not fetch cluster imagelist")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Compute the locks on the side, so that a failed reconcile keeps the
+	// previous locks in place and only reports the failure. A spec that was
+	// not computed yet needs every namespace.
+	var status batchv1.ClusterImmutableImagesStatus
+	var reason string
+	var err error
+	if req.Namespace != "" && images.Status.ObservedGeneration == images.Generation {
+		status, reason, err = r.computeNamespaceLocks(ctx, images, req.Namespace)
+	} else {
+		status, reason, err = r.computeLocks(ctx, images)
+	}
+	if err == nil {
+		status.Conditions = images.Status.Conditions
+		status.AuditViolations = images.Status.AuditViolations
+		status.ObservedGeneration = images.Generation
+		images.Status = status
+	}
+	var statuses []*batchv1.LockStatus
+	for i := range images.Status.Namespaces {
+		statuses = append(statuses, &images.Status.Namespaces[i].LockStatus)
+	}
+	r.namespaced().setConditions(&images.Status.Conditions, images.Generation,
+		images.Spec.RulesEnforcementMode(), images.Status.LockedSecretCount, reason, err, statuses...)
+
+	if updateErr := r.Status().Update(ctx, images); updateErr != nil {
+		log.Error(updateErr, "Could not update cluster immutable secret list")
+		return ctrl.Result{}, updateErr
+	}
+	if err != nil {
+		log.Error(err, "Could not compute locks", "reason", reason)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Computes the locks of every selected namespace, on failure the reason for
+// the Degraded condition is returned along the error
+func (r *ClusterImmutableImagesReconciler) computeLocks(ctx context.Context, images *batchv1.ClusterImmutableImages) (batchv1.ClusterImmutableImagesStatus, string, error) {
+	status := batchv1.ClusterImmutableImagesStatus{}
+
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaceList); err != nil {
+		return status, batchv1.ReasonGetNamespaceFailed, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	for _, namespace := range namespaceList.Items {
+		if !matchesSelector(images.Spec.NamespaceSelector, namespace.Labels) {
+			continue
+		}
+		locks, reason, err := r.namespaceLocks(ctx, images, namespace.Name)
+		if err != nil {
+			return status, reason, err
+		}
+		setNamespaceLocks(&status, namespace.Name, locks)
+	}
+	return status, "", nil
+}
+
+// Computes the locks of a single namespace and merges them into the previous
+// status, a namespace that is gone or no longer selected is dropped from it
+func (r *ClusterImmutableImagesReconciler) computeNamespaceLocks(ctx context.Context, images *batchv1.ClusterImmutableImages, namespaceName string) (batchv1.ClusterImmutableImagesStatus, string, error) {
+	status := *images.Status.DeepCopy()
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); err != nil {
+		if !errors.IsNotFound(err) {
+			return status, batchv1.ReasonGetNamespaceFailed, fmt.Errorf("failed to get namespace %s: %w", namespaceName, err)
+		}
+		setNamespaceLocks(&status, namespaceName, nil)
+		return status, "", nil
+	}
+	if !matchesSelector(images.Spec.NamespaceSelector, namespace.Labels) {
+		setNamespaceLocks(&status, namespaceName, nil)
+		return status, "", nil
+	}
+	locks, reason, err := r.namespaceLocks(ctx, images, namespaceName)
+	if err != nil {
+		return status, reason, err
+	}
+	setNamespaceLocks(&status, namespaceName, locks)
+	return status, "", nil
+}
+
+// Computes the locks of a selected namespace, nil when nothing runs a listed
+// image there
+func (r *ClusterImmutableImagesReconciler) namespaceLocks(ctx context.Context, images *batchv1.ClusterImmutableImages, namespace string) (*batchv1.NamespaceLockStatus, string, error) {
+	// The namespace is already selected, the namespaced copy of the CR
+	// must not look it up again
+	local := &batchv1.ImmutableImages{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       images.Name,
+			Namespace:  namespace,
+			Generation: images.Generation,
+		},
+		Spec: *images.Spec.ImmutableImagesSpec.DeepCopy(),
+	}
+	local.Spec.NamespaceSelector = nil
+	// The locks computed before carry the fingerprints of missing secrets
+	if previous, found := images.Status.Namespace(namespace); found {
+		local.Status.LockStatus = *previous.LockStatus.DeepCopy()
+	}
+	if reason, err := r.namespaced().computeLocks(ctx, local); err != nil {
+		return nil, reason, fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+	if local.Status.MatchedPodCount == 0 && local.Status.LockedSecretCount == 0 {
+		return nil, "", nil
+	}
+	truncateConsumers(&local.Status.LockStatus)
+	return &batchv1.NamespaceLockStatus{
+		Namespace:       namespace,
+		MatchedPodCount: local.Status.MatchedPodCount,
+		LockStatus:      local.Status.LockStatus,
+	}, "", nil
+}
+
+// truncateConsumers keeps the first MaxClusterConsumers consumers of every
+// lock and records how many there were
+func truncateConsumers(status *batchv1.LockStatus) {
+	for _, locks := range [][]batchv1.SecretLock{status.LockedSecrets, status.LockedPullSecrets} {
+		for i := range locks {
+			lock := &locks[i]
+			if len(lock.Consumers) <= batchv1.MaxClusterConsumers {
+				continue
+			}
+			pods := sets.New[string]()
+			for _, consumer := range lock.Consumers {
+				pods.Insert(consumer.PodName)
+			}
+			lock.ConsumerCount = len(lock.Consumers)
+			lock.ConsumerPods = sets.List(pods)
+			lock.Consumers = lock.Consumers[:batchv1.MaxClusterConsumers]
+		}
+	}
+}
+
+// setNamespaceLocks replaces the locks of the namespace in the status, nil
+// removes them, and adds up the counts of every namespace again
+func setNamespaceLocks(status *batchv1.ClusterImmutableImagesStatus, namespace string, locks *batchv1.NamespaceLockStatus) {
+	status.Namespaces = slices.DeleteFunc(status.Namespaces, func(existing batchv1.NamespaceLockStatus) bool {
+		return existing.Namespace == namespace
+	})
+	if locks != nil {
+		idx, _ := slices.BinarySearchFunc(status.Namespaces, namespace, func(existing batchv1.NamespaceLockStatus, namespace string) int {
+			return strings.Compare(existing.Namespace, namespace)
+		})
+		status.Namespaces = slices.Insert(status.Namespaces, idx, *locks)
+	}
+	status.LockedSecretCount = 0
+	status.MatchedPodCount = 0
+	for _, existing := range status.Namespaces {
+		status.LockedSecretCount += len(existing.ImmutableSecrets) + len(existing.ImmutablePullSecrets)
+		status.MatchedPodCount += existing.MatchedPodCount
+	}
+}
+
+// Get all cluster images and create a request for them, scoped to the
+// namespace the event came from
+func (r *ClusterImmutableImagesReconciler) requestsForAll(ctx context.Context, obj client.Object) []reconcile.Request {
+	namespace := obj.GetNamespace()
+	if _, ok := obj.(*corev1.Namespace); ok {
+		namespace = obj.GetName()
+	}
+	var clusterList batchv1.ClusterImmutableImagesList
+	if err := r.List(ctx, &clusterList); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, images := range clusterList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: images.Name, Namespace: namespace},
+		})
+	}
+	return requests
+}
+
+// Get the cluster images with a rule whose podSelector matches the pod and create a
+// request for them in the namespace of the pod. Updates map both the old and the new pod.
+func (r *ClusterImmutableImagesReconciler) requestsForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	var clusterList batchv1.ClusterImmutableImagesList
+	if err := r.List(ctx, &clusterList); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, images := range clusterList.Items {
+		if !selectsPodByRule(&images.Spec.ImmutableImagesSpec, pod) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: images.Name, Namespace: pod.Namespace},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterImmutableImagesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueueForAll := handler.EnqueueRequestsFromMapFunc(r.requestsForAll)
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates of the CR itself must not trigger another reconcile
+		For(&batchv1.ClusterImmutableImages{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.requestsForPod)).
+		// Namespaces are selected by label and can come and go
+		Watches(&corev1.Namespace{}, enqueueForAll).
+		Watches(&appsv1.Deployment{}, enqueueForAll).
+		Watches(&appsv1.StatefulSet{}, enqueueForAll).
+		Watches(&appsv1.DaemonSet{}, enqueueForAll).
+		Watches(&appsv1.ReplicaSet{}, enqueueForAll).
+		Watches(&kbatchv1.Job{}, enqueueForAll).
+		Watches(&kbatchv1.CronJob{}, enqueueForAll).
+		Named("clusterimmutableimages").
+		Complete(r)
+}
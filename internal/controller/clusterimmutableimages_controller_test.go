@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ClusterImmutableImages Controller", func() {
+	Context("When a cluster-scoped policy selects namespaces", func() {
+		const (
+			resourceName   = "test-resource-cluster"
+			testNamespace  = "default"
+			testImage      = "cluster:1.0"
+			testSecretName = "test-secret-cluster"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name: resourceName,
+		}
+		clusterimmutableimages := &batchv1.ClusterImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ClusterImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, clusterimmutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ClusterImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: resourceName,
+					},
+					Spec: batchv1.ClusterImmutableImagesSpec{
+						ImmutableImagesSpec: batchv1.ImmutableImagesSpec{
+							Images: []string{
+								testImage,
+							},
+							NamespaceSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{corev1.LabelMetadataName: testNamespace},
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ClusterImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ClusterImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should publish the locks of the selected namespace", func() {
+			By("By creating a Pod running the listed image")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-cluster",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "cluster",
+							Image: testImage,
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: testSecretName},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ClusterImmutableImages{}
+
+			By("Checking the per-namespace status of the CR")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				locks, found := resource.Status.Namespace(testNamespace)
+				g.Expect(found).To(BeTrue(), "namespace should be selected")
+				g.Expect(locks.ImmutableSecrets).To(ContainElement(
+					batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName}))
+				g.Expect(resource.Status.LockedSecretCount).To(Equal(1))
+			}, timeout, interval).Should(Succeed(), "should lock the secret in the selected namespace")
+		})
+	})
+})
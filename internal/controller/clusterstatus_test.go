@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+)
+
+var _ = Describe("Cluster lock status", func() {
+	namespaceLocks := func(namespace string, secrets ...string) *batchv1.NamespaceLockStatus {
+		locks := &batchv1.NamespaceLockStatus{Namespace: namespace, MatchedPodCount: 1}
+		for _, secret := range secrets {
+			locks.ImmutableSecrets = append(locks.ImmutableSecrets, batchv1.NamespacedName{Namespace: namespace, Name: secret})
+		}
+		return locks
+	}
+
+	It("should replace the locks of a single namespace", func() {
+		status := &batchv1.ClusterImmutableImagesStatus{}
+		setNamespaceLocks(status, "team-b", namespaceLocks("team-b", "b-1"))
+		setNamespaceLocks(status, "team-a", namespaceLocks("team-a", "a-1", "a-2"))
+		Expect(status.Namespaces).To(HaveLen(2))
+		Expect(status.Namespaces[0].Namespace).To(Equal("team-a"))
+		Expect(status.LockedSecretCount).To(Equal(3))
+		Expect(status.MatchedPodCount).To(Equal(2))
+
+		setNamespaceLocks(status, "team-a", nil)
+		Expect(status.Namespaces).To(HaveLen(1))
+		Expect(status.LockedSecretCount).To(Equal(1))
+		Expect(status.MatchedPodCount).To(Equal(1))
+	})
+
+	It("should truncate the consumers of a lock", func() {
+		lock := batchv1.SecretLock{Namespace: "team-a", Name: "a-1"}
+		for i := range batchv1.MaxClusterConsumers + 5 {
+			lock.Consumers = append(lock.Consumers, batchv1.SecretConsumer{PodName: fmt.Sprintf("replica-%d", i)})
+		}
+		locks := &batchv1.LockStatus{LockedSecrets: []batchv1.SecretLock{lock}}
+		truncateConsumers(locks)
+		Expect(locks.LockedSecrets[0].Consumers).To(HaveLen(batchv1.MaxClusterConsumers))
+		Expect(locks.LockedSecrets[0].ConsumerCount).To(Equal(batchv1.MaxClusterConsumers + 5))
+		Expect(locks.LockedSecrets[0].ConsumerPods).To(HaveLen(batchv1.MaxClusterConsumers + 5))
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When reporting the state of the locks", func() {
+		const (
+			resourceName   = "test-resource-conditions"
+			testNamespace  = "default"
+			testImage      = "conditions:1.0"
+			testSecretName = "test-secret-conditions"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							testImage,
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should publish the counts and conditions", func() {
+			By("By creating a Pod running the listed image")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-conditions",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "conditions",
+							Image: testImage,
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: testSecretName},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking the status of the CR")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ObservedGeneration).To(Equal(resource.Generation))
+				g.Expect(resource.Status.LockedSecretCount).To(Equal(1))
+				g.Expect(resource.Status.MatchedPodCount).To(Equal(1))
+				g.Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, batchv1.ConditionReady)).To(BeTrue())
+				g.Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, batchv1.ConditionEnforcing)).To(BeTrue())
+				g.Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, batchv1.ConditionDegraded)).To(BeTrue())
+			}, timeout, interval).Should(Succeed(), "should report the locks as ready")
+		})
+	})
+})
@@ -0,0 +1,128 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ConfigMap references", func() {
+	configMapProjection := func(name string) corev1.VolumeProjection {
+		return corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "configmaps", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "kube-api-access-abcde",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{configMapProjection("kube-root-ca.crt")},
+						},
+					},
+				},
+				{
+					Name: "bundle",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								configMapProjection("kube-root-ca.crt"),
+								configMapProjection("app-settings"),
+							},
+						},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:  "app",
+					Image: "nginx:0.3",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "kube-api-access-abcde", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"},
+						{Name: "bundle", MountPath: "/etc/bundle"},
+					},
+				},
+				{
+					Name:  "sidecar",
+					Image: "nginx:0.3",
+					EnvFrom: []corev1.EnvFromSource{
+						{
+							ConfigMapRef: &corev1.ConfigMapEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "sidecar-settings"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	It("should leave out the service account root CA", func() {
+		var names []string
+		for _, ref := range podConfigMapReferences(pod) {
+			names = append(names, ref.Name)
+		}
+		Expect(names).To(ConsistOf("app-settings", "sidecar-settings"))
+	})
+
+	DescribeTable("should apply the exclusions and lock expressions of the CR",
+		func(spec batchv1.ImmutableImagesSpec, expected ...string) {
+			spec.Images = []string{"nginx:0.3"}
+			images := &batchv1.ImmutableImages{Spec: spec}
+			reconciler := &ImmutableImagesReconciler{}
+			Expect(reconciler.fetchPodConfigMaps(context.Background(), images, pod)).To(Succeed())
+			var names []string
+			for _, configMap := range images.Status.ImmutableConfigMaps {
+				names = append(names, configMap.Name)
+			}
+			Expect(names).To(ConsistOf(expected))
+		},
+		Entry("no criteria", batchv1.ImmutableImagesSpec{}, "app-settings", "sidecar-settings"),
+		Entry("excluded container",
+			batchv1.ImmutableImagesSpec{Exclusions: &batchv1.Exclusions{ContainerNames: []string{"side*"}}},
+			"app-settings"),
+		Entry("expression on the source",
+			batchv1.ImmutableImagesSpec{LockExpressions: []string{`reference.source == 'EnvFrom'`}},
+			"sidecar-settings"),
+		Entry("expression on a secret name", batchv1.ImmutableImagesSpec{
+			LockExpressions: []string{`reference.secretName.startsWith('app')`},
+		}),
+	)
+
+	It("should lock a configmap with the strictest mode of the rules locking it", func() {
+		images := &batchv1.ImmutableImages{}
+		images.Spec.EnforcementMode = batchv1.EnforcementModeAudit
+		addConfigMapToList(context.Background(), images, "default", "app-settings")
+		images.Spec.EnforcementMode = batchv1.EnforcementModeWarn
+		addConfigMapToList(context.Background(), images, "default", "app-settings")
+		Expect(images.Status.LockedConfigMaps).To(Equal([]batchv1.ConfigMapLock{
+			{Namespace: "default", Name: "app-settings", EnforcementMode: batchv1.EnforcementModeWarn},
+		}))
+	})
+})
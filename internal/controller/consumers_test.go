@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When several containers mount the same secret volume", func() {
+		const (
+			resourceName   = "test-resource-consumers"
+			testNamespace  = "default"
+			testSecretName = "test-secret-consumers"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"consumer:1.0",
+							"consumer:2.0",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should attribute the secret to every consuming container", func() {
+			By("By creating a Pod with two containers sharing a secret volume")
+			mounts := []corev1.VolumeMount{
+				{
+					Name:      "credentials",
+					MountPath: "/etc/credentials",
+					ReadOnly:  true,
+				},
+			}
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-consumers",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{
+							Name: "credentials",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: testSecretName,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:         "first",
+							Image:        "consumer:1.0",
+							VolumeMounts: mounts,
+						},
+						{
+							Name:         "second",
+							Image:        "consumer:2.0",
+							VolumeMounts: mounts,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that both containers are recorded as consumers")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImageSecretsMap["consumer:1.0"]).To(ContainElement(testSecretName))
+				g.Expect(resource.Status.ImageSecretsMap["consumer:2.0"]).To(ContainElement(testSecretName))
+				idx := slices.IndexFunc(resource.Status.LockedSecrets, func(lock batchv1.SecretLock) bool {
+					return lock.Name == testSecretName
+				})
+				g.Expect(idx).NotTo(Equal(-1), "secret should have a lock entry")
+				g.Expect(resource.Status.LockedSecrets[idx].Consumers).To(ConsistOf(
+					batchv1.SecretConsumer{
+						PodName:       testPod.Name,
+						PodUID:        testPod.UID,
+						Container:     "first",
+						ContainerKind: batchv1.ContainerKindContainer,
+						Image:         "consumer:1.0",
+						Reference:     batchv1.ReferenceKindVolume,
+					},
+					batchv1.SecretConsumer{
+						PodName:       testPod.Name,
+						PodUID:        testPod.UID,
+						Container:     "second",
+						ContainerKind: batchv1.ContainerKindContainer,
+						Image:         "consumer:2.0",
+						Reference:     batchv1.ReferenceKindVolume,
+					},
+				))
+			}, timeout, interval).Should(Succeed(), "should record every consumer of the secret")
+		})
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Enforcing condition", func() {
+	DescribeTable("should follow the enforcement modes of the rules",
+		func(spec batchv1.ImmutableImagesSpec, status metav1.ConditionStatus, reason string) {
+			spec.Images = []string{"nginx:0.3"}
+			var conditions []metav1.Condition
+			reconciler := &ImmutableImagesReconciler{}
+			reconciler.setConditions(&conditions, 1, spec.RulesEnforcementMode(), 0, "", nil)
+			enforcing := meta.FindStatusCondition(conditions, batchv1.ConditionEnforcing)
+			Expect(enforcing).NotTo(BeNil())
+			Expect(enforcing.Status).To(Equal(status))
+			Expect(enforcing.Reason).To(Equal(reason))
+			Expect(meta.IsStatusConditionTrue(conditions, batchv1.ConditionReady)).To(BeTrue())
+		},
+		Entry("unset mode", batchv1.ImmutableImagesSpec{},
+			metav1.ConditionTrue, batchv1.ReasonWebhookEnabled),
+		Entry("warn mode", batchv1.ImmutableImagesSpec{EnforcementMode: batchv1.EnforcementModeWarn},
+			metav1.ConditionFalse, batchv1.ReasonWarnOnly),
+		Entry("audit mode", batchv1.ImmutableImagesSpec{EnforcementMode: batchv1.EnforcementModeAudit},
+			metav1.ConditionFalse, batchv1.ReasonAuditOnly),
+		Entry("audit mode with an enforced rule", batchv1.ImmutableImagesSpec{
+			EnforcementMode: batchv1.EnforcementModeAudit,
+			Rules: []batchv1.ImageRule{
+				{Name: "payments", Images: []string{"payments:1.0"}, EnforcementMode: batchv1.EnforcementModeEnforce},
+			},
+		}, metav1.ConditionTrue, batchv1.ReasonWebhookEnabled),
+		Entry("warn and audit rules", batchv1.ImmutableImagesSpec{
+			EnforcementMode: batchv1.EnforcementModeAudit,
+			Rules: []batchv1.ImageRule{
+				{Name: "payments", Images: []string{"payments:1.0"}, EnforcementMode: batchv1.EnforcementModeWarn},
+			},
+		}, metav1.ConditionFalse, batchv1.ReasonWarnOnly),
+	)
+})
@@ -0,0 +1,94 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"path"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+)
+
+// matchesNamePattern reports whether the name matches one of the glob patterns.
+// Invalid patterns are rejected on admission and never match.
+func matchesNamePattern(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, err := path.Match(pattern, name)
+		return err == nil && matched
+	})
+}
+
+// Checks the reference against the exclusions of the CR and returns the rule
+// that excludes it, if any. Secrets that do not exist yet have no labels.
+func (r *ImmutableImagesReconciler) excludeReference(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod, ref extractor.SecretReference) (batchv1.ExclusionReason, bool, error) {
+	exclusions := images.Spec.Exclusions
+	if exclusions == nil {
+		return "", false, nil
+	}
+	if matchesNamePattern(exclusions.SecretNames, ref.SecretName) {
+		return batchv1.ExclusionReasonSecretName, true, nil
+	}
+	if reason, excluded := excludeConsumer(exclusions, pod, ref.Container); excluded {
+		return reason, true, nil
+	}
+	if exclusions.SecretSelector != nil {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.SecretName, Namespace: pod.Namespace}, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", false, err
+		}
+		if err == nil && matchesSelector(exclusions.SecretSelector, secret.Labels) {
+			return batchv1.ExclusionReasonSecretLabels, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Checks the consuming container and pod against the exclusions, these apply to
+// configmaps as well as secrets
+func excludeConsumer(exclusions *batchv1.Exclusions, pod *corev1.Pod, container string) (batchv1.ExclusionReason, bool) {
+	if exclusions == nil {
+		return "", false
+	}
+	if matchesNamePattern(exclusions.ContainerNames, container) {
+		return batchv1.ExclusionReasonContainerName, true
+	}
+	if exclusions.PodSelector != nil && matchesSelector(exclusions.PodSelector, pod.Labels) {
+		return batchv1.ExclusionReasonPodLabels, true
+	}
+	return "", false
+}
+
+// Add the reference to the skippedReferences of the CR along with the reason
+func addSkippedReference(images *batchv1.ImmutableImages, pod *corev1.Pod, ref extractor.SecretReference, reason batchv1.ExclusionReason) {
+	skipped := batchv1.SkippedReference{
+		Secret:    ref.SecretName,
+		PodName:   pod.Name,
+		Container: ref.Container,
+		Reference: ref.Source,
+		Reason:    reason,
+	}
+	if !slices.Contains(images.Status.SkippedReferences, skipped) {
+		images.Status.SkippedReferences = append(images.Status.SkippedReferences, skipped)
+	}
+}
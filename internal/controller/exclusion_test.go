@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When a listed image references an excluded secret", func() {
+		const (
+			resourceName       = "test-resource-exclusions"
+			testNamespace      = "default"
+			testImage          = "exclusions:1.0"
+			lockedSecretName   = "test-secret-exclusions"
+			excludedSecretName = "test-secret-exclusions-tls"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							testImage,
+						},
+						Exclusions: &batchv1.Exclusions{
+							SecretNames: []string{"*-tls"},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should skip the excluded secret and report why", func() {
+			By("By creating a Pod referencing both secrets")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-exclusions",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "exclusions",
+							Image: testImage,
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: lockedSecretName},
+									},
+								},
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: excludedSecretName},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that only the other secret is locked")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImmutableSecrets).To(ConsistOf(batchv1.NamespacedName{Namespace: testNamespace, Name: lockedSecretName}))
+				g.Expect(resource.Status.SkippedReferences).To(ConsistOf(batchv1.SkippedReference{
+					Secret:    excludedSecretName,
+					PodName:   testPod.Name,
+					Container: "exclusions",
+					Reference: batchv1.ReferenceKindEnvFrom,
+					Reason:    batchv1.ExclusionReasonSecretName,
+				}))
+			}, timeout, interval).Should(Succeed(), "should report the skipped reference")
+		})
+	})
+})
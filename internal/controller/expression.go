@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/expression"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+)
+
+// locksByExpressions reports whether every lock expression of the CR holds for
+// the reference. Expressions that fail to evaluate, e.g. on a missing field,
+// lock the reference and are reported in the status of the CR, a typo must
+// not silently unlock every secret.
+func locksByExpressions(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod, container extractor.Container, reference map[string]any) (bool, error) {
+	if len(images.Spec.LockExpressions) == 0 {
+		return true, nil
+	}
+	podVar, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return false, err
+	}
+	containerVar, err := runtime.DefaultUnstructuredConverter.ToUnstructured(container.Container)
+	if err != nil {
+		return false, err
+	}
+	vars := map[string]any{
+		expression.PodVariable:       podVar,
+		expression.ContainerVariable: containerVar,
+		expression.ReferenceVariable: reference,
+	}
+	for _, expr := range images.Spec.LockExpressions {
+		holds, err := expression.Evaluate(expr, vars)
+		if err != nil {
+			log.FromContext(ctx).Info("Could not evaluate lock expression", "expression", expr, "pod", pod.Name, "error", err.Error())
+			failure := fmt.Sprintf("%s: %v", expr, err)
+			if !slices.Contains(images.Status.ExpressionErrors, failure) {
+				images.Status.ExpressionErrors = append(images.Status.ExpressionErrors, failure)
+			}
+			return true, nil
+		}
+		if !holds {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// secretReferenceVariable is the reference variable of a secret reference,
+// its configMapName is empty.
+func secretReferenceVariable(ref extractor.SecretReference) map[string]any {
+	reference := map[string]any{
+		"secretName":    ref.SecretName,
+		"configMapName": "",
+		"container":     ref.Container,
+		"containerKind": string(ref.ContainerKind),
+		"source":        string(ref.Source),
+		"key":           ref.Key,
+	}
+	if ref.Volume != "" {
+		addMountFields(reference, corev1.VolumeMount{Name: ref.Volume, MountPath: ref.MountPath, ReadOnly: ref.ReadOnly})
+	}
+	return reference
+}
+
+// configMapReferenceVariable is the reference variable of a configmap
+// reference, its secretName is empty so that expressions on it evaluate.
+func configMapReferenceVariable(ref configMapReference) map[string]any {
+	reference := map[string]any{
+		"secretName":    "",
+		"configMapName": ref.Name,
+		"container":     ref.Container.Name,
+		"containerKind": string(ref.Container.Kind),
+		"source":        string(ref.Source),
+		"key":           ref.Key,
+	}
+	if ref.Mount != nil {
+		addMountFields(reference, *ref.Mount)
+	}
+	return reference
+}
+
+// addMountFields sets the volume, mountPath and readOnly fields of a reference
+// consumed through a volume, other references do not have them.
+func addMountFields(reference map[string]any, mount corev1.VolumeMount) {
+	reference["volume"] = mount.Name
+	reference["mountPath"] = mount.MountPath
+	reference["readOnly"] = mount.ReadOnly
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Lock expressions", func() {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "expressions",
+			Annotations: map[string]string{"team": "payments"},
+		},
+	}
+	container := extractor.Container{
+		Container: &corev1.Container{
+			Name:         "api",
+			Image:        "registry.internal/payments/api:1.0",
+			VolumeMounts: []corev1.VolumeMount{{Name: "credentials", ReadOnly: true}},
+		},
+		Kind: batchv1.ContainerKindContainer,
+	}
+	ref := extractor.SecretReference{
+		SecretName:    "payments-credentials",
+		Container:     "api",
+		ContainerKind: batchv1.ContainerKindContainer,
+		Source:        batchv1.ReferenceKindVolume,
+		Volume:        "credentials",
+		MountPath:     "/etc/credentials",
+		ReadOnly:      true,
+	}
+
+	DescribeTable("should decide whether a reference locks its secret",
+		func(expressions []string, expected bool) {
+			images := &batchv1.ImmutableImages{
+				Spec: batchv1.ImmutableImagesSpec{LockExpressions: expressions},
+			}
+			locks, err := locksByExpressions(context.Background(), images, pod, container, secretReferenceVariable(ref))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(locks).To(Equal(expected))
+		},
+		Entry("no expression", nil, true),
+		Entry("internal registry", []string{`container.image.startsWith('registry.internal/')`}, true),
+		Entry("public registry", []string{`container.image.startsWith('docker.io/')`}, false),
+		Entry("read-only mount", []string{`reference.readOnly == true`}, true),
+		Entry("dyn-typed expression", []string{`reference.readOnly`}, true),
+		Entry("mount path", []string{`reference.mountPath.startsWith('/etc/')`}, true),
+		Entry("annotated pod", []string{`pod.metadata.annotations['team'] == 'payments'`}, true),
+		Entry("every expression must hold",
+			[]string{`reference.source == 'Volume'`, `reference.secretName.endsWith('-tls')`}, false),
+	)
+
+	It("should lock the reference and report an expression that fails to evaluate", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{
+				LockExpressions: []string{`pod.metadata.labels['app'] == 'api'`},
+			},
+		}
+		locks, err := locksByExpressions(context.Background(), images, pod, container, secretReferenceVariable(ref))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(BeTrue())
+		Expect(images.Status.ExpressionErrors).To(ConsistOf(HavePrefix(`pod.metadata.labels['app'] == 'api': `)))
+
+		var conditions []metav1.Condition
+		reconciler := &ImmutableImagesReconciler{}
+		reconciler.setConditions(&conditions, 1, batchv1.EnforcementModeEnforce, 1, "", nil, &images.Status.LockStatus)
+		degraded := meta.FindStatusCondition(conditions, batchv1.ConditionDegraded)
+		Expect(degraded).NotTo(BeNil())
+		Expect(degraded.Reason).To(Equal(batchv1.ReasonExpressionError))
+	})
+
+	It("should lock the reference when a dyn-typed expression is not a bool", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{LockExpressions: []string{`reference.mountPath`}},
+		}
+		locks, err := locksByExpressions(context.Background(), images, pod, container, secretReferenceVariable(ref))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(BeTrue())
+		Expect(images.Status.ExpressionErrors).To(ConsistOf(ContainSubstring("must evaluate to bool")))
+	})
+
+	It("should only lock secrets of read-only mounts", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{
+				LockExpressions: []string{`!has(reference.readOnly) || reference.readOnly`},
+			},
+		}
+		writable := ref
+		writable.ReadOnly = false
+		locks, err := locksByExpressions(context.Background(), images, pod, container, secretReferenceVariable(writable))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(BeFalse())
+
+		env := extractor.SecretReference{SecretName: "payments-env", Container: "api", Source: batchv1.ReferenceKindEnv}
+		locks, err = locksByExpressions(context.Background(), images, pod, container, secretReferenceVariable(env))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(locks).To(BeTrue())
+	})
+})
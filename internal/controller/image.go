@@ -0,0 +1,257 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// normalizeImage expands an image reference with the standard Docker grammar,
+// so that "alpine", "alpine:latest" and "docker.io/library/alpine:latest" all
+// become the latter. References that fail to parse are returned unchanged.
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+// parseDigest returns the repository and digest of a digest-pinned reference,
+// such as a "repo@sha256:..." key or the imageID of a container status. The
+// repository is empty for bare "sha256:..." image IDs.
+func parseDigest(ref string) (string, string, bool) {
+	ref = strings.TrimPrefix(ref, "docker-pullable://")
+	if dgst, err := digest.Parse(ref); err == nil {
+		return "", dgst.String(), true
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", "", false
+	}
+	canonical, ok := named.(reference.Canonical)
+	if !ok {
+		return "", "", false
+	}
+	return named.Name(), canonical.Digest().String(), true
+}
+
+// parsedImage is an image reference split into the parts matchers look at.
+type parsedImage struct {
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseImage splits a normalized image reference, an image with neither a tag
+// nor a digest is tagged latest.
+func parseImage(image string) (parsedImage, bool) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return parsedImage{}, false
+	}
+	named = reference.TagNameOnly(named)
+	parsed := parsedImage{repository: named.Name()}
+	if tagged, ok := named.(reference.Tagged); ok {
+		parsed.tag = tagged.Tag()
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		parsed.digest = canonical.Digest().String()
+	}
+	return parsed, true
+}
+
+// keyMatcher returns the matcher an image of Images stands for. A key
+// pinned by digest matches that digest whatever the tag of the image.
+func keyMatcher(key string) (batchv1.ImageMatcher, bool) {
+	parsed, ok := parseImage(key)
+	if !ok {
+		return batchv1.ImageMatcher{}, false
+	}
+	if parsed.digest != "" {
+		return batchv1.ImageMatcher{Repository: parsed.repository, Digest: parsed.digest}, true
+	}
+	return batchv1.ImageMatcher{Repository: parsed.repository, Tag: parsed.tag}, true
+}
+
+// matcherKey identifies a matcher of ImageMatchers in the reports of the CR.
+func matcherKey(matcher batchv1.ImageMatcher) string {
+	key := matcher.Repository
+	if matcher.Tag != "" {
+		key += ":" + matcher.Tag
+	}
+	if matcher.Versions != "" {
+		key += " " + matcher.Versions
+	}
+	if matcher.Digest != "" {
+		key += "@" + matcher.Digest
+	}
+	return key
+}
+
+// compiledVersions caches the parsed Versions constraints of matchers, a
+// constraint that fails to parse is cached as nil.
+var compiledVersions sync.Map
+
+// tagVersion parses the version of an image tag. Everything from the first
+// "-" or "+" on is a variant such as "-alpine" or "-bookworm" rather than a
+// pre-release, so only major.minor.patch is compared.
+func tagVersion(tag string) (semver.Version, error) {
+	core, _, _ := strings.Cut(tag, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return semver.ParseTolerant(core)
+}
+
+// matchImageMatcher reports whether the image of a container matches the
+// matcher. nonSemver is set when the image is only matched or rejected
+// because its tag is not a semantic version.
+func matchImageMatcher(matcher batchv1.ImageMatcher, image, imageID string) (matched, nonSemver bool) {
+	parsed, ok := parseImage(image)
+	if !ok {
+		return false, false
+	}
+	repository := matcher.Repository
+	if parsedRepository, ok := parseImage(repository); ok {
+		repository = parsedRepository.repository
+	}
+	if parsed.repository != repository {
+		return false, false
+	}
+	// Digests are matched against the imageID the container runtime resolved
+	// the image to as well, so they follow the exact bits that are running
+	if matcher.Digest != "" && parsed.digest != matcher.Digest {
+		name, dgst, ok := parseDigest(imageID)
+		if !ok || dgst != matcher.Digest || (name != "" && name != repository) {
+			return false, false
+		}
+	}
+	if matcher.Tag != "" && parsed.tag != matcher.Tag {
+		return false, false
+	}
+	if matcher.Versions == "" {
+		return true, false
+	}
+
+	version, err := tagVersion(parsed.tag)
+	if err != nil {
+		return matcher.NonSemverTags == batchv1.NonSemverTagMatch, true
+	}
+	cached, ok := compiledVersions.Load(matcher.Versions)
+	if !ok {
+		versions, _ := semver.ParseRange(matcher.Versions)
+		cached, _ = compiledVersions.LoadOrStore(matcher.Versions, versions)
+	}
+	versions := cached.(semver.Range)
+	return versions != nil && versions(version), false
+}
+
+// matchImage returns the image of Images matching the image once both
+// are normalized, or else the first matcher of ImageMatchers or pattern of
+// ImagePatterns matching it. Every image matches, under an empty key, when
+// the selectors or lock expressions of the CR replace image matching.
+func matchImage(images *batchv1.ImmutableImages, image, imageID string) (string, bool) {
+	if slices.Contains(images.Spec.Images, image) {
+		return image, true
+	}
+	// Sorted so that the same key wins when several of them match
+	for _, key := range sets.List(sets.New(images.Spec.Images...)) {
+		matcher, ok := keyMatcher(key)
+		if !ok {
+			continue
+		}
+		if matched, _ := matchImageMatcher(matcher, image, imageID); matched {
+			return key, true
+		}
+	}
+	for _, matcher := range images.Spec.ImageMatchers {
+		if matched, _ := matchImageMatcher(matcher, image, imageID); matched {
+			return matcherKey(matcher), true
+		}
+	}
+	normalized := normalizeImage(image)
+	for _, pattern := range images.Spec.ImagePatterns {
+		if matchImagePattern(pattern, image) || matchImagePattern(pattern, normalized) {
+			return pattern.Pattern, true
+		}
+	}
+	return "", selectsAllImages(images)
+}
+
+// compiledPatterns caches the regular expressions of image patterns, a
+// pattern that fails to compile is cached as nil.
+var compiledPatterns sync.Map
+
+// matchImagePattern reports whether the image matches the pattern. Invalid
+// patterns are rejected on admission and never match.
+func matchImagePattern(pattern batchv1.ImagePattern, image string) bool {
+	if pattern.Type == batchv1.ImagePatternRegex {
+		cached, ok := compiledPatterns.Load(pattern.Pattern)
+		if !ok {
+			compiled, _ := regexp.Compile("^(?:" + pattern.Pattern + ")$")
+			cached, _ = compiledPatterns.LoadOrStore(pattern.Pattern, compiled)
+		}
+		re := cached.(*regexp.Regexp)
+		return re != nil && re.MatchString(image)
+	}
+	matched, err := path.Match(pattern.Pattern, image)
+	return err == nil && matched
+}
+
+// Add the images of the pod matching a pattern or a matcher to matchedImages,
+// and those whose tag is not a semantic version to nonSemverTags
+func recordImageMatches(images *batchv1.ImmutableImages, pod *corev1.Pod) {
+	for _, container := range extractor.Containers(pod) {
+		normalized := normalizeImage(container.Image)
+		for _, pattern := range images.Spec.ImagePatterns {
+			if matchImagePattern(pattern, container.Image) || matchImagePattern(pattern, normalized) {
+				addMatchedImage(images, pattern.Pattern, container.Image)
+			}
+		}
+		for _, matcher := range images.Spec.ImageMatchers {
+			matched, nonSemver := matchImageMatcher(matcher, container.Image, container.ImageID)
+			if matched {
+				addMatchedImage(images, matcherKey(matcher), container.Image)
+			}
+			if nonSemver && !slices.Contains(images.Status.NonSemverTags, container.Image) {
+				images.Status.NonSemverTags = append(images.Status.NonSemverTags, container.Image)
+			}
+		}
+	}
+}
+
+// Add the image to the matchedImages of the given pattern or matcher
+func addMatchedImage(images *batchv1.ImmutableImages, key, image string) {
+	if images.Status.MatchedImages == nil {
+		images.Status.MatchedImages = map[string][]string{}
+	}
+	if !slices.Contains(images.Status.MatchedImages[key], image) {
+		images.Status.MatchedImages[key] = append(images.Status.MatchedImages[key], image)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Image matching", func() {
+	DescribeTable("should normalize image references",
+		func(image, expected string) {
+			Expect(normalizeImage(image)).To(Equal(expected))
+		},
+		Entry("short name", "alpine", "docker.io/library/alpine:latest"),
+		Entry("short name with tag", "alpine:3.20", "docker.io/library/alpine:3.20"),
+		Entry("fully qualified", "docker.io/library/alpine:latest", "docker.io/library/alpine:latest"),
+		Entry("legacy index domain", "index.docker.io/library/alpine", "docker.io/library/alpine:latest"),
+		Entry("user repository", "bitnami/nginx", "docker.io/bitnami/nginx:latest"),
+		Entry("private registry", "registry.internal:5000/payments/api", "registry.internal:5000/payments/api:latest"),
+		Entry("unparsable", "Not A Reference", "Not A Reference"),
+	)
+
+	It("should match differently spelled images to the CR key", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{
+				Images: []string{"alpine:latest"},
+			},
+		}
+		for _, image := range []string{"alpine", "docker.io/library/alpine:latest", "index.docker.io/library/alpine"} {
+			key, found := matchImage(images, image, "")
+			Expect(found).To(BeTrue(), image)
+			Expect(key).To(Equal("alpine:latest"))
+		}
+		_, found := matchImage(images, "alpine:edge", "")
+		Expect(found).To(BeFalse())
+	})
+
+	DescribeTable("should match digest keys",
+		func(image, imageID string, expected bool) {
+			const dgst = "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270"
+			images := &batchv1.ImmutableImages{
+				Spec: batchv1.ImmutableImagesSpec{
+					Images: []string{"alpine@" + dgst},
+				},
+			}
+			_, found := matchImage(images, image, imageID)
+			Expect(found).To(Equal(expected))
+		},
+		Entry("resolved imageID", "alpine:latest",
+			"docker.io/library/alpine@sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", true),
+		Entry("docker-pullable imageID", "alpine:latest",
+			"docker-pullable://alpine@sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", true),
+		Entry("bare digest imageID", "alpine:latest",
+			"sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", true),
+		Entry("digest-pinned spec image with a tag", "alpine:3.20@sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270",
+			"", true),
+		Entry("different build of the same tag", "alpine:latest",
+			"docker.io/library/alpine@sha256:0000000000000000000000000000000000000000000000000000000000000000", false),
+		Entry("same digest in another repository", "busybox:latest",
+			"docker.io/library/busybox@sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", false),
+		Entry("tag only before the status is reported", "alpine:latest", "", false),
+	)
+
+	DescribeTable("should match structured image matchers",
+		func(matcher batchv1.ImageMatcher, image string, expected, nonSemver bool) {
+			matched, seenNonSemver := matchImageMatcher(matcher, image, "")
+			Expect(matched).To(Equal(expected))
+			Expect(seenNonSemver).To(Equal(nonSemver))
+		},
+		Entry("any tag of a repository", batchv1.ImageMatcher{Repository: "registry.internal/auth"},
+			"registry.internal/auth:2024-06-01", true, false),
+		Entry("exact tag", batchv1.ImageMatcher{Repository: "nginx", Tag: "1.25.3"},
+			"docker.io/library/nginx:1.25.3", true, false),
+		Entry("lower bound of a range", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:1.24.0", true, false),
+		Entry("excluded upper bound of a range", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:1.26.0", false, false),
+		Entry("lenient tags", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:v1.25", true, false),
+		Entry("variant suffix at the lower bound", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:1.24.0-alpine", true, false),
+		Entry("variant suffix at the upper bound", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:1.26.0-alpine", false, false),
+		Entry("lenient tags with a variant suffix", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+			"nginx:1.25-alpine", true, false),
+		Entry("non semver tags are ignored by default", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0"},
+			"nginx:latest", false, true),
+		Entry("non semver tags can match", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0", NonSemverTags: batchv1.NonSemverTagMatch},
+			"nginx:mainline", true, true),
+		Entry("other repository", batchv1.ImageMatcher{Repository: "nginx", Versions: ">=1.24.0"},
+			"bitnami/nginx:1.25.0", false, false),
+	)
+
+	It("should report non semver tags seen by a matcher", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{
+				ImageMatchers: []batchv1.ImageMatcher{{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"}},
+			},
+		}
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "web", Image: "nginx:1.25.3"},
+					{Name: "canary", Image: "nginx:latest"},
+				},
+			},
+		}
+		recordImageMatches(images, pod)
+		Expect(images.Status.MatchedImages).To(Equal(map[string][]string{
+			"nginx >=1.24.0 <1.26.0": {"nginx:1.25.3"},
+		}))
+		Expect(images.Status.NonSemverTags).To(ConsistOf("nginx:latest"))
+	})
+
+	DescribeTable("should match image patterns",
+		func(pattern batchv1.ImagePattern, image string, expected bool) {
+			images := &batchv1.ImmutableImages{
+				Spec: batchv1.ImmutableImagesSpec{
+					ImagePatterns: []batchv1.ImagePattern{pattern},
+				},
+			}
+			key, found := matchImage(images, image, "")
+			Expect(found).To(Equal(expected))
+			if expected {
+				Expect(key).To(Equal(pattern.Pattern))
+			}
+		},
+		Entry("glob on a repository", batchv1.ImagePattern{Pattern: "registry.internal/payments/*"},
+			"registry.internal/payments/api:1.2", true),
+		Entry("glob does not cross a slash", batchv1.ImagePattern{Pattern: "registry.internal/*"},
+			"registry.internal/payments/api:1.2", false),
+		Entry("glob on the normalized image", batchv1.ImagePattern{Pattern: "docker.io/library/nginx:*"},
+			"nginx:1.25", true),
+		Entry("regex on tags", batchv1.ImagePattern{Pattern: `nginx:1\.2[4-5]\..*`, Type: batchv1.ImagePatternRegex},
+			"nginx:1.25.3", true),
+		Entry("regex must match the whole image", batchv1.ImagePattern{Pattern: "nginx", Type: batchv1.ImagePatternRegex},
+			"nginx:1.25.3", false),
+		Entry("invalid regex never matches", batchv1.ImagePattern{Pattern: "nginx(", Type: batchv1.ImagePatternRegex},
+			"nginx(", false),
+	)
+
+	It("should report the concrete images matched by each pattern", func() {
+		images := &batchv1.ImmutableImages{
+			Spec: batchv1.ImmutableImagesSpec{
+				ImagePatterns: []batchv1.ImagePattern{{Pattern: "registry.internal/payments/*"}},
+			},
+		}
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "api", Image: "registry.internal/payments/api:1.2"},
+					{Name: "sidecar", Image: "envoy:1.30"},
+				},
+			},
+		}
+		recordImageMatches(images, pod)
+		Expect(images.Status.MatchedImages).To(Equal(map[string][]string{
+			"registry.internal/payments/*": {"registry.internal/payments/api:1.2"},
+		}))
+	})
+})
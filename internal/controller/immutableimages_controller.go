@@ -20,18 +20,27 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/fingerprint"
+	"github.com/brongulus/secret-controller/pkg/extractor"
+	appsv1 "k8s.io/api/apps/v1"
+	kbatchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -39,96 +48,472 @@ import (
 type ImmutableImagesReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Extractors find the secrets referenced by a pod, extractor.Defaults()
+	// are used when nil. Set it to register extractors for in-house conventions.
+	Extractors []extractor.SecretReferenceExtractor
+
+	// WebhooksDisabled is set when the manager does not serve the validating
+	// webhooks, so that the CRs report that their locks are not enforced.
+	WebhooksDisabled bool
 }
 
 // +kubebuilder:rbac:groups=batch.github.com,resources=immutableimages,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch.github.com,resources=immutableimages/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=batch.github.com,resources=immutableimages/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=pods,verbs=watch;create;list;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch
 
-// Add the given secret to the immutableSecretsList
-func (r *ImmutableImagesReconciler) addSecretToImageMap(ctx context.Context, images *batchv1.ImmutableImages, imageName, secretName string) error {
+// Add the given secret to the immutableSecretsList and
+// under imageKey, the key of ImageSecretsMap matching the image of the consumer
+func (r *ImmutableImagesReconciler) addSecretToImageMap(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod, imageKey, image string, ref extractor.SecretReference) error {
 	// log := log.FromContext(ctx)
-	if !slices.Contains(images.Spec.ImmutableSecrets, secretName) {
-		images.Spec.ImmutableSecrets = append(images.Spec.ImmutableSecrets, secretName)
+	secretName := ref.SecretName
+	secret := batchv1.NamespacedName{Namespace: pod.Namespace, Name: secretName}
+	if !slices.Contains(images.Status.ImmutableSecrets, secret) {
+		images.Status.ImmutableSecrets = append(images.Status.ImmutableSecrets, secret)
 		fmt.Printf("Adding secret %s to immutableSecrets\n", secretName)
 	}
 
-	if _, found := images.Spec.ImageSecretsMap[imageName]; found {
-		if !slices.Contains(images.Spec.ImageSecretsMap[imageName], secretName) {
+	idx := slices.IndexFunc(images.Status.LockedSecrets, func(lock batchv1.SecretLock) bool {
+		return lock.Namespace == pod.Namespace && lock.Name == secretName
+	})
+	if idx < 0 {
+		images.Status.LockedSecrets = append(images.Status.LockedSecrets, batchv1.SecretLock{
+			Namespace:       pod.Namespace,
+			Name:            secretName,
+			EnforcementMode: batchv1.StrictestEnforcementMode(images.Spec.EnforcementMode),
+		})
+		idx = len(images.Status.LockedSecrets) - 1
+	}
+	lock := &images.Status.LockedSecrets[idx]
+	// A secret locked by several rules follows the strictest of them
+	lock.EnforcementMode = batchv1.StrictestEnforcementMode(lock.EnforcementMode, images.Spec.EnforcementMode)
+	if !slices.Contains(lock.ContainerKinds, ref.ContainerKind) {
+		lock.ContainerKinds = append(lock.ContainerKinds, ref.ContainerKind)
+	}
+	// An empty key means the whole secret is consumed
+	if ref.Key == "" {
+		lock.AllKeys = true
+	} else if !slices.Contains(lock.Keys, ref.Key) {
+		lock.Keys = append(lock.Keys, ref.Key)
+	}
+	consumer := batchv1.SecretConsumer{
+		PodName:       pod.Name,
+		PodUID:        pod.UID,
+		Container:     ref.Container,
+		ContainerKind: ref.ContainerKind,
+		Image:         image,
+		Reference:     ref.Source,
+	}
+	if !slices.Contains(lock.Consumers, consumer) {
+		lock.Consumers = append(lock.Consumers, consumer)
+	}
+
+	if _, found := images.Status.ImageSecretsMap[imageKey]; found {
+		if !slices.Contains(images.Status.ImageSecretsMap[imageKey], secretName) {
 			// fmt.Printf("Adding secret %s to status\n", secretName)
-			images.Spec.ImageSecretsMap[imageName] = append(images.Spec.ImageSecretsMap[imageName], secretName)
+			images.Status.ImageSecretsMap[imageKey] = append(images.Status.ImageSecretsMap[imageKey], secretName)
 		}
 	}
 	return nil
 }
 
+// Add the given configmap to the immutableConfigMapsList and record the
+// enforcement mode of its lock
+func addConfigMapToList(ctx context.Context, images *batchv1.ImmutableImages, namespace, configMapName string) {
+	configMap := batchv1.NamespacedName{Namespace: namespace, Name: configMapName}
+	if !slices.Contains(images.Status.ImmutableConfigMaps, configMap) {
+		images.Status.ImmutableConfigMaps = append(images.Status.ImmutableConfigMaps, configMap)
+		log.FromContext(ctx).V(1).Info("Adding configmap to immutableConfigMaps", "configMap", configMapName)
+	}
+
+	idx := slices.IndexFunc(images.Status.LockedConfigMaps, func(lock batchv1.ConfigMapLock) bool {
+		return lock.Namespace == namespace && lock.Name == configMapName
+	})
+	if idx < 0 {
+		images.Status.LockedConfigMaps = append(images.Status.LockedConfigMaps, batchv1.ConfigMapLock{
+			Namespace:       namespace,
+			Name:            configMapName,
+			EnforcementMode: batchv1.StrictestEnforcementMode(images.Spec.EnforcementMode),
+		})
+		return
+	}
+	// A configmap locked by several rules follows the strictest of them
+	lock := &images.Status.LockedConfigMaps[idx]
+	lock.EnforcementMode = batchv1.StrictestEnforcementMode(lock.EnforcementMode, images.Spec.EnforcementMode)
+}
+
 // Checks if there are secrets for the pod satisfying the
 // immutableimage criteria, add to immutableSecretsList
 func (r *ImmutableImagesReconciler) fetchPodSecrets(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod) (sets.Set[string], error) {
 	// log := log.FromContext(ctx)
 	secretList := sets.New[string]()
+	containers := extractor.Containers(pod)
+
+	extractors := r.Extractors
+	if extractors == nil {
+		extractors = extractor.Defaults()
+	}
+	for _, refExtractor := range extractors {
+		for _, ref := range refExtractor.ExtractSecretReferences(pod) {
+			// Container names are unique across all container kinds of a pod
+			idx := slices.IndexFunc(containers, func(container extractor.Container) bool {
+				return container.Name == ref.Container
+			})
+			if idx < 0 {
+				continue
+			}
+			container := containers[idx]
+			// Check if image is part of immutable map
+			imageKey, found := matchImage(images, container.Image, container.ImageID)
+			if !found {
+				continue
+			}
+			// The container list is the source of truth for the kind
+			ref.ContainerKind = container.Kind
+			reason, excluded, err := r.excludeReference(ctx, images, pod, ref)
+			if err != nil {
+				return secretList, err
+			}
+			if excluded {
+				addSkippedReference(images, pod, ref, reason)
+				continue
+			}
+			locks, err := locksByExpressions(ctx, images, pod, container, secretReferenceVariable(ref))
+			if err != nil {
+				return secretList, err
+			}
+			if !locks {
+				continue
+			}
+			secretList.Insert(ref.SecretName)
+			if err := r.addSecretToImageMap(ctx, images, pod, imageKey, container.Image, ref); err != nil {
+				return secretList, err
+			}
+		}
+	}
+
+	return secretList, nil
+}
+
+// The ServiceAccount admission plugin injects a kube-api-access projected
+// volume into every pod, its kube-root-ca.crt configmap is rewritten by the
+// control plane on CA rotation and must never be locked.
+const (
+	serviceAccountVolumePrefix = "kube-api-access-"
+	rootCAConfigMapName        = "kube-root-ca.crt"
+)
+
+// configMapReference is a configmap consumed by a container of a pod.
+type configMapReference struct {
+	Name      string
+	Container extractor.Container
+	Source    batchv1.ReferenceKind
+	Key       string
+	// Mount is the mount of Volume and Projected references.
+	Mount *corev1.VolumeMount
+}
+
+// podConfigMapReferences returns the configmaps consumed by the containers of
+// the pod, leaving out the service account root CA.
+func podConfigMapReferences(pod *corev1.Pod) []configMapReference {
+	var refs []configMapReference
+	containers := extractor.Containers(pod)
 
-	// pod.Volumes.Secret.SecretName
 	for _, volume := range pod.Spec.Volumes {
-		if volume.Secret != nil {
-			// DONE Check if the particular volume has an associated immutable image
-			imageName := ""
-			hasImmutableImage := slices.ContainsFunc(pod.Spec.Containers, func(container corev1.Container) bool {
-				for _, mount := range container.VolumeMounts {
-					if mount.Name == volume.Name {
-						// Check if image is part of immutable map
-						if _, found := images.Spec.ImageSecretsMap[container.Image]; found {
-							imageName = container.Image
-							return true
-						}
-					}
+		var volumeRefs []configMapReference
+		// pod.Volumes.ConfigMap.Name
+		if volume.ConfigMap != nil {
+			volumeRefs = append(volumeRefs, configMapReference{Name: volume.ConfigMap.Name, Source: batchv1.ReferenceKindVolume})
+		}
+		// pod.Volumes.Projected.Sources.ConfigMap.Name
+		if volume.Projected != nil && !strings.HasPrefix(volume.Name, serviceAccountVolumePrefix) {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					volumeRefs = append(volumeRefs, configMapReference{Name: source.ConfigMap.Name, Source: batchv1.ReferenceKindProjected})
 				}
-				return false
-			})
-			if hasImmutableImage {
-				secretName := volume.Secret.SecretName
-				secretList.Insert(secretName)
-				if err := r.addSecretToImageMap(ctx, images, imageName, secretName); err != nil {
-					return secretList, err
+			}
+		}
+		for _, container := range containers {
+			for _, mount := range extractor.VolumeMounts(container, volume.Name) {
+				for _, ref := range volumeRefs {
+					ref.Container = container
+					ref.Mount = &mount
+					refs = append(refs, ref)
 				}
 			}
 		}
 	}
 
-	// Ref: https://stackoverflow.com/questions/46406596/how-to-identify-unused-secrets-in-kubernetes
-	// Ref: https://kubernetes.io/docs/tasks/inject-data-application/distribute-credentials-secure/
-	for _, container := range pod.Spec.Containers {
-		// Check if image is part of immutable map
-		_, hasImmutableImage := images.Spec.ImageSecretsMap[container.Image]
-		imageName := container.Image
-		// pod.Containers.Env.ValueFrom.SecretKeyRef.Name
+	for _, container := range containers {
+		// pod.Containers.Env.ValueFrom.ConfigMapKeyRef.Name
 		for _, env := range container.Env {
-			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && hasImmutableImage {
-				secretName := env.ValueFrom.SecretKeyRef.Name
-				secretList.Insert(secretName)
-				if err := r.addSecretToImageMap(ctx, images, imageName, secretName); err != nil {
-					return secretList, err
-				}
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				refs = append(refs, configMapReference{
+					Name:      env.ValueFrom.ConfigMapKeyRef.Name,
+					Container: container,
+					Source:    batchv1.ReferenceKindEnv,
+					Key:       env.ValueFrom.ConfigMapKeyRef.Key,
+				})
 			}
 		}
-		// pod.Containers.EnvFrom.SecretRef.Name
-		// Use envFrom to define all of the Secret's data as container environment variables.
-		// The key from the Secret becomes the environment variable name in the Pod.
+		// pod.Containers.EnvFrom.ConfigMapRef.Name
 		for _, envFrom := range container.EnvFrom {
-			if envFrom.SecretRef != nil && hasImmutableImage {
-				secretName := envFrom.SecretRef.Name
-				secretList.Insert(secretName)
-				if err := r.addSecretToImageMap(ctx, images, imageName, secretName); err != nil {
-					return secretList, err
-				}
+			if envFrom.ConfigMapRef != nil {
+				refs = append(refs, configMapReference{
+					Name:      envFrom.ConfigMapRef.Name,
+					Container: container,
+					Source:    batchv1.ReferenceKindEnvFrom,
+				})
 			}
 		}
 	}
 
+	return slices.DeleteFunc(refs, func(ref configMapReference) bool {
+		return ref.Name == rootCAConfigMapName
+	})
+}
+
+// Checks if there are configmaps for the pod satisfying the
+// immutableimage criteria, add to immutableConfigMapsList
+func (r *ImmutableImagesReconciler) fetchPodConfigMaps(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod) error {
+	for _, ref := range podConfigMapReferences(pod) {
+		// Check if image is part of immutable map
+		if _, found := matchImage(images, ref.Container.Image, ref.Container.ImageID); !found {
+			continue
+		}
+		if _, excluded := excludeConsumer(images.Spec.Exclusions, pod, ref.Container.Name); excluded {
+			continue
+		}
+		locks, err := locksByExpressions(ctx, images, pod, ref.Container, configMapReferenceVariable(ref))
+		if err != nil {
+			return err
+		}
+		if !locks {
+			continue
+		}
+		addConfigMapToList(ctx, images, pod.Namespace, ref.Name)
+	}
+	return nil
+}
+
+// Checks if the pod runs an immutable image and adds the registry
+// credentials it pulls with to the immutablePullSecrets list
+func (r *ImmutableImagesReconciler) fetchPodPullSecrets(ctx context.Context, images *batchv1.ImmutableImages, pod *corev1.Pod) (sets.Set[string], error) {
+	secretList := sets.New[string]()
+
+	var containers []extractor.Container
+	for _, container := range extractor.Containers(pod) {
+		if _, found := matchImage(images, container.Image, container.ImageID); found {
+			containers = append(containers, container)
+		}
+	}
+	if len(containers) == 0 {
+		return secretList, nil
+	}
+
+	// pod.ImagePullSecrets.Name
+	names := sets.New[string]()
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		names.Insert(ref.Name)
+	}
+
+	// serviceAccount.ImagePullSecrets.Name are merged into the pod on admission,
+	// look them up as well in case the pod predates them
+	saName := pod.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, types.NamespacedName{Name: saName, Namespace: pod.Namespace}, serviceAccount); err != nil {
+		if !errors.IsNotFound(err) {
+			return secretList, fmt.Errorf("failed to get service account %s: %w", saName, err)
+		}
+	} else {
+		for _, ref := range serviceAccount.ImagePullSecrets {
+			names.Insert(ref.Name)
+		}
+	}
+
+	// A pull secret is locked unless the exclusions skip it for every
+	// container whose image matches
+	for _, secretName := range sets.List(names) {
+		var skipped, consumers []extractor.SecretReference
+		var reasons []batchv1.ExclusionReason
+		for _, container := range containers {
+			ref := extractor.SecretReference{
+				SecretName:    secretName,
+				Container:     container.Name,
+				ContainerKind: container.Kind,
+				Source:        batchv1.ReferenceKindImagePullSecret,
+			}
+			reason, excluded, err := r.excludeReference(ctx, images, pod, ref)
+			if err != nil {
+				return secretList, err
+			}
+			if excluded {
+				skipped = append(skipped, ref)
+				reasons = append(reasons, reason)
+				continue
+			}
+			consumers = append(consumers, ref)
+		}
+		if len(consumers) == 0 {
+			for i, ref := range skipped {
+				addSkippedReference(images, pod, ref, reasons[i])
+			}
+			continue
+		}
+		secretList.Insert(secretName)
+		secret := batchv1.NamespacedName{Namespace: pod.Namespace, Name: secretName}
+		if !slices.Contains(images.Status.ImmutablePullSecrets, secret) {
+			images.Status.ImmutablePullSecrets = append(images.Status.ImmutablePullSecrets, secret)
+		}
+		addPullSecretLock(images, pod, containers, consumers)
+	}
 	return secretList, nil
 }
 
+// Record the pod pulling with the secret in the lockedPullSecrets of the CR,
+// so that the secret is protected from deletion and pinned like other locks
+func addPullSecretLock(images *batchv1.ImmutableImages, pod *corev1.Pod, containers []extractor.Container, refs []extractor.SecretReference) {
+	secretName := refs[0].SecretName
+	idx := slices.IndexFunc(images.Status.LockedPullSecrets, func(lock batchv1.SecretLock) bool {
+		return lock.Namespace == pod.Namespace && lock.Name == secretName
+	})
+	if idx < 0 {
+		images.Status.LockedPullSecrets = append(images.Status.LockedPullSecrets, batchv1.SecretLock{
+			Namespace: pod.Namespace,
+			Name:      secretName,
+			// Registry credentials are consumed as a whole
+			AllKeys:         true,
+			EnforcementMode: batchv1.StrictestEnforcementMode(images.Spec.EnforcementMode),
+		})
+		idx = len(images.Status.LockedPullSecrets) - 1
+	}
+	lock := &images.Status.LockedPullSecrets[idx]
+	lock.EnforcementMode = batchv1.StrictestEnforcementMode(lock.EnforcementMode, images.Spec.EnforcementMode)
+	for _, ref := range refs {
+		if !slices.Contains(lock.ContainerKinds, ref.ContainerKind) {
+			lock.ContainerKinds = append(lock.ContainerKinds, ref.ContainerKind)
+		}
+		image := ""
+		if i := slices.IndexFunc(containers, func(container extractor.Container) bool {
+			return container.Name == ref.Container
+		}); i >= 0 {
+			image = containers[i].Image
+		}
+		consumer := batchv1.SecretConsumer{
+			PodName:       pod.Name,
+			PodUID:        pod.UID,
+			Container:     ref.Container,
+			ContainerKind: ref.ContainerKind,
+			Image:         image,
+			Reference:     ref.Source,
+		}
+		if !slices.Contains(lock.Consumers, consumer) {
+			lock.Consumers = append(lock.Consumers, consumer)
+		}
+	}
+}
+
+// templatePod wraps the pod template of a workload into a pod named after the
+// workload, so that it can go through the same discovery as running pods.
+func templatePod(workload metav1.ObjectMeta, template *corev1.PodTemplateSpec) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        workload.Name,
+			Namespace:   workload.Namespace,
+			UID:         workload.UID,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: template.Spec,
+	}
+}
+
+// Lists the pod templates of the live workloads in the namespace, this lets
+// secrets get locked as soon as a workload is created instead of waiting
+// for its first pod to be scheduled. Workloads owned by another listed one,
+// scaled down ReplicaSets and finished Jobs are left out
+func (r *ImmutableImagesReconciler) fetchWorkloadPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, workload := range deployments.Items {
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.Template))
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, workload := range statefulSets.Items {
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.Template))
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := r.List(ctx, daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, workload := range daemonSets.Items {
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.Template))
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := r.List(ctx, replicaSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	for _, workload := range replicaSets.Items {
+		// The Deployment owning a ReplicaSet is listed already, and scaled down
+		// revisions only linger for rollbacks
+		if metav1.GetControllerOf(&workload) != nil ||
+			(workload.Spec.Replicas != nil && *workload.Spec.Replicas == 0) {
+			continue
+		}
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.Template))
+	}
+
+	jobs := &kbatchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for _, workload := range jobs.Items {
+		// The CronJob owning a Job is listed already, and finished Jobs do
+		// not start pods anymore
+		if metav1.GetControllerOf(&workload) != nil || jobFinished(&workload) {
+			continue
+		}
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.Template))
+	}
+
+	cronJobs := &kbatchv1.CronJobList{}
+	if err := r.List(ctx, cronJobs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, workload := range cronJobs.Items {
+		pods = append(pods, templatePod(workload.ObjectMeta, &workload.Spec.JobTemplate.Spec.Template))
+	}
+
+	return pods, nil
+}
+
+// Whether the job completed or failed
+func jobFinished(job *kbatchv1.Job) bool {
+	return slices.ContainsFunc(job.Status.Conditions, func(condition kbatchv1.JobCondition) bool {
+		return (condition.Type == kbatchv1.JobComplete || condition.Type == kbatchv1.JobFailed) &&
+			condition.Status == corev1.ConditionTrue
+	})
+}
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 // DONE(user): Modify the Reconcile function to compare the state specified by
@@ -152,72 +537,308 @@ func (r *ImmutableImagesReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		log.Info("Ignoring not found since imagelist is deleted or not created")
 		return ctrl.Result{}, nil
 	}
+
+	// DONE: Compute the locks on a copy, so that a failed reconcile keeps the
+	// previous locks in place and only reports the failure
+	computed := images.DeepCopy()
+	reason, err := r.computeLocks(ctx, computed)
+	if err == nil {
+		images.Status = computed.Status
+		images.Status.ObservedGeneration = images.Generation
+	}
+	r.setConditions(&images.Status.Conditions, images.Generation, images.Spec.RulesEnforcementMode(), images.Status.LockedSecretCount,
+		reason, err, &images.Status.LockStatus)
+
+	if updateErr := r.Status().Update(ctx, images); updateErr != nil { // DONE
+		log.Error(updateErr, "Could not update immutable secret list")
+		return ctrl.Result{}, updateErr
+	}
+	if err != nil {
+		log.Error(err, "Could not compute locks", "reason", reason)
+		return ctrl.Result{}, err
+	}
+	log.V(1).Info(">>> Reconcile Over")
+	fmt.Println("=======================================")
+	return ctrl.Result{}, nil
+}
+
+// Computes the status of the CR from the pods and workloads of its namespace,
+// on failure the reason for the Degraded condition is returned along the error
+func (r *ImmutableImagesReconciler) computeLocks(ctx context.Context, images *batchv1.ImmutableImages) (string, error) {
 	// DONE: Updates to the CR
-	// DONE: Start with a clean slate
-	for image := range images.Spec.ImageSecretsMap {
-		images.Spec.ImageSecretsMap[image] = []string{}
+	// DONE: Start with a clean slate, the spec is left to the user and the
+	// computed locks are published in the status
+	// The fingerprints of missing secrets can only come from the previous status
+	pinned := map[batchv1.NamespacedName]string{}
+	for _, locks := range [][]batchv1.SecretLock{images.Status.LockedSecrets, images.Status.LockedPullSecrets} {
+		for _, lock := range locks {
+			pinned[batchv1.NamespacedName{Namespace: lock.Namespace, Name: lock.Name}] = lock.ContentHash
+		}
+	}
+	rules := images.Spec.ImageRules()
+	imageSecretsMap := map[string][]string{}
+	for _, rule := range rules {
+		for _, image := range rule.Images {
+			imageSecretsMap[image] = []string{}
+		}
+	}
+	images.Status = batchv1.ImmutableImagesStatus{
+		Conditions: images.Status.Conditions,
+		// Audit violations are recorded by the webhooks
+		AuditViolations: images.Status.AuditViolations,
+		LockStatus:      batchv1.LockStatus{ImageSecretsMap: imageSecretsMap},
 	}
-	images.Spec.ImmutableSecrets = nil
 	// fmt.Printf("---------- Reset CR ---------\n")
 
+	// DONE: Evaluate every rule on its own, only in namespaces matching its namespaceSelector
+	var scopes []*batchv1.ImmutableImages
+	for _, rule := range rules {
+		scope := ruleScope(images, rule)
+		selected, err := r.selectsNamespace(ctx, scope, images.Namespace)
+		if err != nil {
+			return batchv1.ReasonGetNamespaceFailed, fmt.Errorf("failed to get namespace: %w", err)
+		}
+		if selected {
+			scopes = append(scopes, scope)
+		}
+	}
+
 	podList := &corev1.PodList{}
-	if err := r.List(ctx, podList, client.InNamespace(req.Namespace)); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to list pods: %w", err)
+	if err := r.List(ctx, podList, client.InNamespace(images.Namespace)); err != nil {
+		return batchv1.ReasonListPodsFailed, fmt.Errorf("failed to list pods: %w", err)
 	}
 
-	for _, pod := range podList.Items {
-		fmt.Printf("Pod is %s\n", pod.Name)
-		// Get list of all the secrets attached to a pod
-		secretList, err := r.fetchPodSecrets(ctx, images, &pod)
-		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to get pod secrets: %w", err)
+	// DONE: Lock secrets of workloads before their pods exist
+	workloadPods, err := r.fetchWorkloadPods(ctx, images.Namespace)
+	if err != nil {
+		return batchv1.ReasonListWorkloadsFailed, err
+	}
+
+	for i, pod := range append(podList.Items, workloadPods...) {
+		matched := false
+		for _, scope := range scopes {
+			if !selectsPod(scope, &pod) {
+				continue
+			}
+			fmt.Printf("Pod is %s\n", pod.Name)
+			// The locks of every rule add up in the status of the CR
+			scope.Status = images.Status
+			// Template pods of workloads are not counted as matched pods
+			if i < len(podList.Items) && slices.ContainsFunc(extractor.Containers(&pod), func(container extractor.Container) bool {
+				_, found := matchImage(scope, container.Image, container.ImageID)
+				return found
+			}) {
+				matched = true
+			}
+			// Get list of all the secrets attached to a pod
+			secretList, err := r.fetchPodSecrets(ctx, scope, &pod)
+			if err != nil {
+				return batchv1.ReasonFetchSecretsFailed, fmt.Errorf("failed to get pod secrets: %w", err)
+			}
+			for secret := range secretList {
+				fmt.Printf("Secret is %s\n", secret)
+			}
+			if err := r.fetchPodConfigMaps(ctx, scope, &pod); err != nil {
+				return batchv1.ReasonFetchSecretsFailed, fmt.Errorf("failed to get pod configmaps: %w", err)
+			}
+			recordImageMatches(scope, &pod)
+			if scope.Spec.LockImagePullSecrets {
+				if _, err := r.fetchPodPullSecrets(ctx, scope, &pod); err != nil {
+					return batchv1.ReasonFetchSecretsFailed, fmt.Errorf("failed to get pod image pull secrets: %w", err)
+				}
+			}
+			images.Status = scope.Status
 		}
-		for secret := range secretList {
-			fmt.Printf("Secret is %s\n", secret)
+		if matched {
+			images.Status.MatchedPodCount++
 		}
 	}
-	if err := r.Update(ctx, images); err != nil { // DONE
-		log.Error(err, "Could not update immutable secret list")
-		return ctrl.Result{}, err
+
+	// DONE: Pin the content of every locked secret, so that it cannot be
+	// deleted and recreated with other data, image pull secrets included
+	for _, locks := range [][]batchv1.SecretLock{images.Status.LockedSecrets, images.Status.LockedPullSecrets} {
+		for i := range locks {
+			lock := &locks[i]
+			secret := &corev1.Secret{}
+			err := r.Get(ctx, types.NamespacedName{Namespace: lock.Namespace, Name: lock.Name}, secret)
+			previous := pinned[batchv1.NamespacedName{Namespace: lock.Namespace, Name: lock.Name}]
+			switch {
+			case err == nil:
+				// A pinned fingerprint is never replaced by the live data, which
+				// may have been tampered with, unless changes are allowed anyway
+				live := fingerprint.Lock(images.Spec.Granularity, lock, secret.Data)
+				if previous == "" || batchv1.StrictestEnforcementMode(lock.EnforcementMode) != batchv1.EnforcementModeEnforce {
+					lock.ContentHash = live
+					break
+				}
+				lock.ContentHash = previous
+				lock.ContentMismatch = live != previous
+				if lock.ContentMismatch {
+					log.FromContext(ctx).Info("Locked secret does not match its pinned content",
+						"secret", lock.Namespace+"/"+lock.Name)
+				}
+			case errors.IsNotFound(err):
+				lock.ContentHash = previous
+			default:
+				return batchv1.ReasonFetchSecretsFailed, fmt.Errorf("failed to get secret %s: %w", lock.Name, err)
+			}
+		}
 	}
-	log.V(1).Info(">>> Reconcile Over")
-	fmt.Println("=======================================")
-	return ctrl.Result{}, nil
+	images.Status.LockedSecretCount = len(images.Status.ImmutableSecrets) + len(images.Status.ImmutablePullSecrets)
+	return "", nil
+}
+
+// Copy of the CR whose criteria are the ones of the given rule, the lock
+// helpers only look at the top level criteria of the spec
+func ruleScope(images *batchv1.ImmutableImages, rule batchv1.ImageRule) *batchv1.ImmutableImages {
+	scope := &batchv1.ImmutableImages{
+		ObjectMeta: *images.ObjectMeta.DeepCopy(),
+		Spec: batchv1.ImmutableImagesSpec{
+			LockImagePullSecrets: images.Spec.LockImagePullSecrets,
+			Granularity:          images.Spec.Granularity,
+			DeletionProtection:   images.Spec.DeletionProtection,
+			EnforcementMode:      images.Spec.RuleEnforcementMode(rule),
+		},
+	}
+	scope.Spec.SetDefaultRule(rule)
+	return scope
+}
+
+// Set the Ready, Enforcing and Degraded conditions of a CR from the outcome
+// of computeLocks, the strictest mode of its rules and whether the webhooks
+// are served
+func (r *ImmutableImagesReconciler) setConditions(conditions *[]metav1.Condition, generation int64, mode batchv1.EnforcementMode, lockedSecretCount int, reason string, err error, statuses ...*batchv1.LockStatus) {
+	enforcing := metav1.Condition{
+		Type:    batchv1.ConditionEnforcing,
+		Status:  metav1.ConditionTrue,
+		Reason:  batchv1.ReasonWebhookEnabled,
+		Message: "Updates to locked secrets are rejected by the webhook",
+	}
+	switch {
+	case r.WebhooksDisabled:
+		enforcing.Status = metav1.ConditionFalse
+		enforcing.Reason = batchv1.ReasonWebhookDisabled
+		enforcing.Message = "Webhooks are disabled with ENABLE_WEBHOOKS=false, locked secrets can still be updated"
+	case mode == batchv1.EnforcementModeWarn:
+		enforcing.Status = metav1.ConditionFalse
+		enforcing.Reason = batchv1.ReasonWarnOnly
+		enforcing.Message = "No rule is in Enforce mode, updates to locked secrets are allowed with a warning"
+	case mode == batchv1.EnforcementModeAudit:
+		enforcing.Status = metav1.ConditionFalse
+		enforcing.Reason = batchv1.ReasonAuditOnly
+		enforcing.Message = "Every rule is in Audit mode, updates to locked secrets are allowed and recorded"
+	}
+
+	degraded := metav1.Condition{
+		Type:    batchv1.ConditionDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  batchv1.ReasonReconciled,
+		Message: "Locks are up to date",
+	}
+	switch {
+	case err != nil:
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = reason
+		degraded.Message = err.Error()
+	case contentMismatches(statuses...) > 0:
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = batchv1.ReasonContentMismatch
+		degraded.Message = fmt.Sprintf("%d locked secrets do not match their pinned content", contentMismatches(statuses...))
+	case len(expressionErrors(statuses...)) > 0:
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = batchv1.ReasonExpressionError
+		degraded.Message = "Lock expressions failed to evaluate, the references they failed on are locked: " +
+			strings.Join(expressionErrors(statuses...), "; ")
+	}
+
+	ready := metav1.Condition{
+		Type:    batchv1.ConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  batchv1.ReasonReconciled,
+		Message: fmt.Sprintf("%d secrets are locked", lockedSecretCount),
+	}
+	switch {
+	case degraded.Status == metav1.ConditionTrue:
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	case enforcing.Reason == batchv1.ReasonWebhookDisabled:
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = enforcing.Reason
+		ready.Message = enforcing.Message
+	}
+
+	for _, condition := range []metav1.Condition{ready, enforcing, degraded} {
+		condition.ObservedGeneration = generation
+		meta.SetStatusCondition(conditions, condition)
+	}
+}
+
+// expressionErrors lists the lock expressions that failed to evaluate
+func expressionErrors(statuses ...*batchv1.LockStatus) []string {
+	failures := sets.New[string]()
+	for _, status := range statuses {
+		failures.Insert(status.ExpressionErrors...)
+	}
+	return sets.List(failures)
+}
+
+// contentMismatches counts the locks whose secret no longer matches its pinned content
+func contentMismatches(statuses ...*batchv1.LockStatus) int {
+	mismatched := 0
+	for _, status := range statuses {
+		for _, locks := range [][]batchv1.SecretLock{status.LockedSecrets, status.LockedPullSecrets} {
+			for _, lock := range locks {
+				if lock.ContentMismatch {
+					mismatched++
+				}
+			}
+		}
+	}
+	return mismatched
+}
+
+// Get all images in the namespace of the object and create a request for them
+func (r *ImmutableImagesReconciler) requestsForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	var immutableList batchv1.ImmutableImagesList
+	if err := r.List(ctx, &immutableList, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, immutable := range immutableList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      immutable.Name,
+				Namespace: obj.GetNamespace(),
+			},
+		})
+	}
+
+	if requests == nil {
+		return nil
+	}
+	fmt.Printf(">>> %T is %s <<<\n", obj, obj.GetName())
+	fmt.Printf("Requested imagelist is : %v\n\n", requests)
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ImmutableImagesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Ref: https://squiggly.dev/2023/07/enqueue-your-father-was-a-mapfunc/
+	enqueueForNamespace := handler.EnqueueRequestsFromMapFunc(r.requestsForNamespace)
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&batchv1.ImmutableImages{}).
-		Watches(
-			&corev1.Pod{}, // Ref: https://squiggly.dev/2023/07/enqueue-your-father-was-a-mapfunc/
-			handler.EnqueueRequestsFromMapFunc(
-				func(ctx context.Context, obj client.Object) []reconcile.Request {
-					pod := obj.(*corev1.Pod)
-					// Get all images in pod namespace and create a request for them
-					var immutableList batchv1.ImmutableImagesList
-					if err := r.List(ctx, &immutableList, client.InNamespace(pod.Namespace)); err != nil {
-						return nil
-					}
-					var requests []reconcile.Request
-					for _, immutable := range immutableList.Items {
-						requests = append(requests, reconcile.Request{
-							NamespacedName: types.NamespacedName{
-								Name:      immutable.Name,
-								Namespace: pod.Namespace,
-							},
-						})
-					}
-
-					if requests == nil {
-						return nil
-					}
-					fmt.Printf(">>> Pod is %s <<<\n", pod.Name)
-					fmt.Printf("Requested imagelist is : %v\n\n", requests)
-					return requests
-				},
-			),
-		).
+		// Status updates of the CR itself must not trigger another reconcile
+		For(&batchv1.ImmutableImages{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Pod status updates are enqueued as well, so that digest keys follow
+		// the imageID the container runtime resolved a tag to
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.requestsForPod)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.requestsForNamespaceLabels)).
+		Watches(&appsv1.Deployment{}, enqueueForNamespace).
+		Watches(&appsv1.StatefulSet{}, enqueueForNamespace).
+		Watches(&appsv1.DaemonSet{}, enqueueForNamespace).
+		Watches(&appsv1.ReplicaSet{}, enqueueForNamespace).
+		Watches(&kbatchv1.Job{}, enqueueForNamespace).
+		Watches(&kbatchv1.CronJob{}, enqueueForNamespace).
 		Named("immutableimages").
 		Complete(r)
 }
@@ -0,0 +1,245 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When a pod consumes secrets from an init container", func() {
+		const (
+			resourceName   = "test-resource-init"
+			testNamespace  = "default"
+			testSecretName = "test-secret-init"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"migrate:1.0",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should lock the secrets of init containers running a listed image", func() {
+			By("By creating a new Secret")
+			testSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+				Type: "Opaque",
+			}
+			Expect(k8sClient.Create(ctx, testSecret)).To(Succeed())
+
+			By("By creating a Pod whose init container uses the secret")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-init",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:  "migrate",
+							Image: "migrate:1.0",
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{
+											Name: testSecretName,
+										},
+									},
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "busybox:latest",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that the secret is locked by an init container")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName})).To(Equal(true), "secret should be in Immutable list")
+				idx := slices.IndexFunc(resource.Status.LockedSecrets, func(lock batchv1.SecretLock) bool {
+					return lock.Name == testSecretName
+				})
+				g.Expect(idx).NotTo(Equal(-1), "secret should have a lock entry")
+				g.Expect(resource.Status.LockedSecrets[idx].ContainerKinds).To(ConsistOf(batchv1.ContainerKindInitContainer))
+			}, timeout, interval).Should(Succeed(), "should lock the secret of the init container")
+		})
+	})
+
+	Context("When a pod consumes secrets from an ephemeral container only", func() {
+		const (
+			resourceName   = "test-resource-ephemeral"
+			testNamespace  = "default"
+			testSecretName = "test-secret-ephemeral"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"debug:1.0",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should lock the secrets of ephemeral containers running a listed image", func() {
+			By("By creating a new Secret")
+			testSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+				Type: "Opaque",
+			}
+			Expect(k8sClient.Create(ctx, testSecret)).To(Succeed())
+
+			By("By creating a Pod whose regular container does not use the secret")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-ephemeral",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "busybox:latest",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			By("By attaching an ephemeral container that uses the secret")
+			testPod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+				{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name:  "debugger",
+						Image: "debug:1.0",
+						EnvFrom: []corev1.EnvFromSource{
+							{
+								SecretRef: &corev1.SecretEnvSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: testSecretName,
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.SubResource("ephemeralcontainers").Update(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that the secret is locked by an ephemeral container")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName})).To(Equal(true), "secret should be in Immutable list")
+				idx := slices.IndexFunc(resource.Status.LockedSecrets, func(lock batchv1.SecretLock) bool {
+					return lock.Name == testSecretName
+				})
+				g.Expect(idx).NotTo(Equal(-1), "secret should have a lock entry")
+				g.Expect(resource.Status.LockedSecrets[idx].ContainerKinds).To(ConsistOf(batchv1.ContainerKindEphemeralContainer))
+			}, timeout, interval).Should(Succeed(), "should lock the secret of the ephemeral container")
+		})
+	})
+})
@@ -64,9 +64,9 @@ var _ = Describe("ImmutableImages Controller", func() {
 					},
 					// TODO(user): Specify other spec details if needed.
 					Spec: batchv1.ImmutableImagesSpec{
-						ImageSecretsMap: map[string][]string{
-							"alpine:latest": {},
-							"nginx:0.3":     {},
+						Images: []string{
+							"alpine:latest",
+							"nginx:0.3",
 						},
 					},
 				}
@@ -191,9 +191,9 @@ var _ = Describe("ImmutableImages Controller", func() {
 					secretLookupKey := types.NamespacedName{Name: testSecretName + fmt.Sprint(i), Namespace: testNamespace}
 					g.Expect(k8sClient.Get(ctx, secretLookupKey, &createdSecretList[i])).To(Succeed(), "should GET the Secret")
 					if i == 1 { // alpine:edge
-						g.Expect(slices.Contains(resource.Spec.ImmutableSecrets, createdSecretList[i].Name)).To(Equal(false), "secret should not be in Immutable list")
+						g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: createdSecretList[i].Name})).To(Equal(false), "secret should not be in Immutable list")
 					} else {
-						g.Expect(slices.Contains(resource.Spec.ImmutableSecrets, createdSecretList[i].Name)).To(Equal(true), "secret should be in Immutable list")
+						g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: createdSecretList[i].Name})).To(Equal(true), "secret should be in Immutable list")
 					}
 				}
 			}, timeout, interval).Should(Succeed(), "should attach our secret to the pod")
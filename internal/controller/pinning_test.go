@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/fingerprint"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Content pinning", func() {
+	var (
+		reconciler *ImmutableImagesReconciler
+		secret     *corev1.Secret
+		request    ctrl.Request
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+		images := &batchv1.ImmutableImages{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinning", Namespace: "default", Generation: 1},
+			Spec:       batchv1.ImmutableImagesSpec{Images: []string{"pinning:1.0"}},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinning", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "app",
+						Image: "pinning:1.0",
+						EnvFrom: []corev1.EnvFromSource{
+							{
+								SecretRef: &corev1.SecretEnvSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "pinned"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinned", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("original")},
+		}
+		reconciler = &ImmutableImagesReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithStatusSubresource(&batchv1.ImmutableImages{}).
+				WithObjects(images, pod, secret).Build(),
+			Scheme: scheme,
+		}
+		request = ctrl.Request{NamespacedName: types.NamespacedName{Name: "pinning", Namespace: "default"}}
+	})
+
+	It("should keep the pinned content when the secret is changed behind the webhook", func() {
+		ctx := context.Background()
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret.Data["password"] = []byte("tampered")
+		Expect(reconciler.Update(ctx, secret)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		images := &batchv1.ImmutableImages{}
+		Expect(reconciler.Get(ctx, request.NamespacedName, images)).To(Succeed())
+		Expect(images.Status.LockedSecrets).To(HaveLen(1))
+		lock := images.Status.LockedSecrets[0]
+		Expect(lock.ContentHash).To(Equal(fingerprint.Data(map[string][]byte{"password": []byte("original")})))
+		Expect(lock.ContentMismatch).To(BeTrue())
+		degraded := meta.FindStatusCondition(images.Status.Conditions, batchv1.ConditionDegraded)
+		Expect(degraded).NotTo(BeNil())
+		Expect(degraded.Reason).To(Equal(batchv1.ReasonContentMismatch))
+	})
+
+	It("should only pin the consumed keys at key granularity", func() {
+		ctx := context.Background()
+		images := &batchv1.ImmutableImages{}
+		Expect(reconciler.Get(ctx, request.NamespacedName, images)).To(Succeed())
+		images.Spec.Granularity = batchv1.LockGranularityKey
+		Expect(reconciler.Update(ctx, images)).To(Succeed())
+		pod := &corev1.Pod{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "pinning", Namespace: "default"}, pod)).To(Succeed())
+		pod.Spec.Containers[0].EnvFrom = nil
+		pod.Spec.Containers[0].Env = []corev1.EnvVar{
+			{
+				Name: "PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "pinned"},
+						Key:                  "password",
+					},
+				},
+			},
+		}
+		Expect(reconciler.Update(ctx, pod)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Keys that are not consumed can be changed through the webhook
+		secret.Data["username"] = []byte("admin")
+		Expect(reconciler.Update(ctx, secret)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(reconciler.Get(ctx, request.NamespacedName, images)).To(Succeed())
+		Expect(images.Status.LockedSecrets).To(HaveLen(1))
+		Expect(images.Status.LockedSecrets[0].ContentMismatch).To(BeFalse())
+		Expect(meta.IsStatusConditionFalse(images.Status.Conditions, batchv1.ConditionDegraded)).To(BeTrue())
+	})
+})
@@ -62,9 +62,9 @@ var _ = Describe("ImmutableImages Controller", func() {
 					},
 					// TODO(user): Specify other spec details if needed.
 					Spec: batchv1.ImmutableImagesSpec{
-						ImageSecretsMap: map[string][]string{
-							"alpine:latest": {},
-							"nginx:0.3":     {},
+						Images: []string{
+							"alpine:latest",
+							"nginx:0.3",
 						},
 					},
 				}
@@ -209,9 +209,9 @@ var _ = Describe("ImmutableImages Controller", func() {
 			Eventually(func(g Gomega) {
 				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
 				g.Expect(k8sClient.Get(ctx, secretLookupKey, createdSecret)).To(Succeed(), "should GET the Secret")
-				g.Expect(slices.Contains(resource.Spec.ImmutableSecrets, createdSecret.Name)).To(Equal(true), "secret should be in Immutable list")
+				g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: createdSecret.Name})).To(Equal(true), "secret should be in Immutable list")
 				g.Expect(k8sClient.Get(ctx, secretLookupKey2, createdSecret2)).To(Succeed(), "should GET the Secret")
-				g.Expect(slices.Contains(resource.Spec.ImmutableSecrets, createdSecret2.Name)).To(Equal(true), "secret should be in Immutable list")
+				g.Expect(slices.Contains(resource.Status.ImmutableSecrets, batchv1.NamespacedName{Namespace: testNamespace, Name: createdSecret2.Name})).To(Equal(true), "secret should be in Immutable list")
 			}, timeout, interval).Should(Succeed(), "should attach our secret to the pod")
 
 		})
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When a pod mounts secrets through a projected volume", func() {
+		const (
+			resourceName   = "test-resource-projected"
+			testNamespace  = "default"
+			testSecretName = "test-secret-projected"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"projected:1.0",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should lock every secret source of the projected volume", func() {
+			By("By creating a Pod with a projected volume of two secrets and a configmap")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-projected",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{
+							Name: "all-in-one",
+							VolumeSource: corev1.VolumeSource{
+								Projected: &corev1.ProjectedVolumeSource{
+									Sources: []corev1.VolumeProjection{
+										{
+											Secret: &corev1.SecretProjection{
+												LocalObjectReference: corev1.LocalObjectReference{
+													Name: testSecretName + "-a",
+												},
+											},
+										},
+										{
+											ConfigMap: &corev1.ConfigMapProjection{
+												LocalObjectReference: corev1.LocalObjectReference{
+													Name: "test-configmap-projected",
+												},
+											},
+										},
+										{
+											Secret: &corev1.SecretProjection{
+												LocalObjectReference: corev1.LocalObjectReference{
+													Name: testSecretName + "-b",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "projected:1.0",
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "all-in-one",
+									MountPath: "/projected-volume",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that both secret sources are locked")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImmutableSecrets).To(ContainElements(
+					batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName + "-a"},
+					batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName + "-b"},
+				))
+				g.Expect(resource.Status.ImmutableSecrets).NotTo(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: "test-configmap-projected"}))
+				g.Expect(resource.Status.ImageSecretsMap["projected:1.0"]).To(HaveLen(2))
+			}, timeout, interval).Should(Succeed(), "should lock the projected secrets")
+		})
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When a CR opts into locking image pull secrets", func() {
+		const (
+			resourceName   = "test-resource-pull"
+			testNamespace  = "default"
+			testSecretName = "test-secret-pull"
+			skippedName    = "test-secret-pull-skipped"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"registry.internal/pull:1.0",
+						},
+						LockImagePullSecrets: true,
+						Exclusions: &batchv1.Exclusions{
+							SecretNames: []string{"*-skipped"},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should report pull secrets separately from application secrets", func() {
+			By("By creating a Pod pulling a listed image with registry credentials")
+			testPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-pull",
+					Namespace: testNamespace,
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: testSecretName},
+						{Name: skippedName},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "registry.internal/pull:1.0",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testPod)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that the pull secret is locked as a pull secret only")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImmutablePullSecrets).To(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName}))
+				g.Expect(resource.Status.ImmutableSecrets).NotTo(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName}))
+			}, timeout, interval).Should(Succeed(), "should lock the image pull secret")
+
+			By("Checking that the pod pulling with the secret holds its lock")
+			Expect(resource.Status.LockedPullSecrets).To(ContainElement(And(
+				HaveField("Name", testSecretName),
+				HaveField("Consumers", ContainElement(HaveField("PodName", "test-pod-pull"))),
+			)))
+
+			By("Checking that the excluded pull secret is skipped")
+			Expect(resource.Status.ImmutablePullSecrets).NotTo(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: skippedName}))
+			Expect(resource.Status.SkippedReferences).To(ContainElement(batchv1.SkippedReference{
+				Secret:    skippedName,
+				PodName:   "test-pod-pull",
+				Container: "app",
+				Reference: batchv1.ReferenceKindImagePullSecret,
+				Reason:    batchv1.ExclusionReasonSecretName,
+			}))
+		})
+	})
+})
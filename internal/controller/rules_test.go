@@ -0,0 +1,145 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When locks are described by named rules", func() {
+		const (
+			resourceName      = "test-resource-rules"
+			testNamespace     = "default"
+			imageSecretName   = "test-secret-rule-image"
+			labelSecretName   = "test-secret-rule-label"
+			skippedSecretName = "test-secret-rule-skipped"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						EnforcementMode: batchv1.EnforcementModeWarn,
+						Rules: []batchv1.ImageRule{
+							{
+								Name:   "by-image",
+								Images: []string{"rules-image:1.0"},
+							},
+							{
+								Name: "by-label",
+								PodSelector: &metav1.LabelSelector{
+									MatchLabels: map[string]string{"app": "rules"},
+								},
+								EnforcementMode: batchv1.EnforcementModeAudit,
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should lock the secrets matched by any rule", func() {
+			envFromPod := func(name, image, secretName string, labels map[string]string) *corev1.Pod {
+				return &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: testNamespace,
+						Labels:    labels,
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "rules",
+								Image: image,
+								EnvFrom: []corev1.EnvFromSource{
+									{
+										SecretRef: &corev1.SecretEnvSource{
+											LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+										},
+									},
+								},
+							},
+						},
+					},
+				}
+			}
+			By("By creating a Pod for each rule and one matched by none")
+			Expect(k8sClient.Create(ctx, envFromPod("test-pod-rule-image", "rules-image:1.0", imageSecretName, nil))).To(Succeed())
+			Expect(k8sClient.Create(ctx, envFromPod("test-pod-rule-label", "other:1.0", labelSecretName,
+				map[string]string{"app": "rules"}))).To(Succeed())
+			Expect(k8sClient.Create(ctx, envFromPod("test-pod-rule-skipped", "other:1.0", skippedSecretName, nil))).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that the secrets of both rules are locked")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImmutableSecrets).To(ContainElements(
+					batchv1.NamespacedName{Namespace: testNamespace, Name: imageSecretName},
+					batchv1.NamespacedName{Namespace: testNamespace, Name: labelSecretName},
+				))
+				g.Expect(resource.Status.ImmutableSecrets).NotTo(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: skippedSecretName}))
+				g.Expect(resource.Status.ImageSecretsMap).To(HaveKeyWithValue("rules-image:1.0", []string{imageSecretName}))
+			}, timeout, interval).Should(Succeed(), "should lock the secrets of every rule")
+
+			By("Checking that every lock follows the enforcement mode of its rule")
+			modes := map[string]batchv1.EnforcementMode{}
+			for _, lock := range resource.Status.LockedSecrets {
+				modes[lock.Name] = lock.EnforcementMode
+			}
+			Expect(modes).To(HaveKeyWithValue(imageSecretName, batchv1.EnforcementModeWarn))
+			Expect(modes).To(HaveKeyWithValue(labelSecretName, batchv1.EnforcementModeAudit))
+		})
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+)
+
+// matchesSelector reports whether the labels match the selector, a nil
+// selector matches everything. Invalid selectors are rejected on admission
+// and never match.
+func matchesSelector(selector *metav1.LabelSelector, set map[string]string) bool {
+	if selector == nil {
+		return true
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return parsed.Matches(labels.Set(set))
+}
+
+// selectsPod reports whether the pod matches the podSelector of the CR.
+func selectsPod(images *batchv1.ImmutableImages, pod *corev1.Pod) bool {
+	return matchesSelector(images.Spec.PodSelector, pod.Labels)
+}
+
+// selectsPodByRule reports whether the podSelector of any rule of the spec
+// matches the pod.
+func selectsPodByRule(spec *batchv1.ImmutableImagesSpec, pod *corev1.Pod) bool {
+	return slices.ContainsFunc(spec.ImageRules(), func(rule batchv1.ImageRule) bool {
+		return matchesSelector(rule.PodSelector, pod.Labels)
+	})
+}
+
+// selectsAllImages reports whether the selectors or lock expressions of the CR
+// replace image matching, which is the case when they are set and no image is
+// listed.
+func selectsAllImages(images *batchv1.ImmutableImages) bool {
+	hasSelector := images.Spec.PodSelector != nil || images.Spec.NamespaceSelector != nil ||
+		len(images.Spec.LockExpressions) > 0
+	hasImages := len(images.Spec.Images) > 0 ||
+		len(images.Spec.ImagePatterns) > 0 || len(images.Spec.ImageMatchers) > 0
+	return hasSelector && !hasImages
+}
+
+// Checks if the namespace matches the namespaceSelector of the CR
+func (r *ImmutableImagesReconciler) selectsNamespace(ctx context.Context, images *batchv1.ImmutableImages, namespace string) (bool, error) {
+	if images.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	return matchesSelector(images.Spec.NamespaceSelector, ns.Labels), nil
+}
+
+// Get the images in the namespace of the pod with a rule whose podSelector matches it and
+// create a request for them. Updates map both the old and the new pod, so a CR
+// releases its locks once the labels of a pod stop matching.
+func (r *ImmutableImagesReconciler) requestsForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	var immutableList batchv1.ImmutableImagesList
+	if err := r.List(ctx, &immutableList, client.InNamespace(pod.Namespace)); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, immutable := range immutableList.Items {
+		if !selectsPodByRule(&immutable.Spec, pod) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      immutable.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// Get the images living in the namespace with a rule selecting namespaces by label and
+// create a request for them, so that they follow changes to its labels
+func (r *ImmutableImagesReconciler) requestsForNamespaceLabels(ctx context.Context, obj client.Object) []reconcile.Request {
+	var immutableList batchv1.ImmutableImagesList
+	if err := r.List(ctx, &immutableList, client.InNamespace(obj.GetName())); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, immutable := range immutableList.Items {
+		if !slices.ContainsFunc(immutable.Spec.ImageRules(), func(rule batchv1.ImageRule) bool {
+			return rule.NamespaceSelector != nil
+		}) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      immutable.Name,
+				Namespace: immutable.Namespace,
+			},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	kbatchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Controller", func() {
+	Context("When a workload is created before any of its pods", func() {
+		const (
+			resourceName   = "test-resource-workload"
+			testNamespace  = "default"
+			testSecretName = "test-secret-workload"
+
+			timeout  = time.Second * 10
+			interval = time.Millisecond * 250
+		)
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: testNamespace,
+		}
+		immutableimages := &batchv1.ImmutableImages{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind ImmutableImages")
+			err := k8sClient.Get(ctx, typeNamespacedName, immutableimages)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: testNamespace,
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"workload:1.0",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance ImmutableImages")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should lock the secrets of the deployment pod template", func() {
+			By("By creating a Deployment, envtest never schedules its pods")
+			labels := map[string]string{"app": "workload"}
+			testDeployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-deployment-workload",
+					Namespace: testNamespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "app",
+									Image: "workload:1.0",
+									Env: []corev1.EnvVar{
+										{
+											Name: "PASSWORD",
+											ValueFrom: &corev1.EnvVarSource{
+												SecretKeyRef: &corev1.SecretKeySelector{
+													Key: "password",
+													LocalObjectReference: corev1.LocalObjectReference{
+														Name: testSecretName,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, testDeployment)).To(Succeed())
+
+			resource := &batchv1.ImmutableImages{}
+
+			By("Checking that the template secret is locked")
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed(), "should GET the CR")
+				g.Expect(resource.Status.ImmutableSecrets).To(ContainElement(batchv1.NamespacedName{Namespace: testNamespace, Name: testSecretName}))
+				g.Expect(resource.Status.ImageSecretsMap["workload:1.0"]).To(ContainElement(testSecretName))
+			}, timeout, interval).Should(Succeed(), "should lock the secret of the workload")
+		})
+	})
+	Context("When a namespace holds workloads that no longer start pods", func() {
+		template := corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "workload:1.0"}},
+			},
+		}
+		owner := metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "web",
+			UID:        "web-uid",
+			Controller: ptr.To(true),
+		}
+
+		It("should only list the pod templates of live workloads", func() {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			reconciler := &ImmutableImagesReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					&appsv1.ReplicaSet{
+						ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "default"},
+						Spec:       appsv1.ReplicaSetSpec{Template: template},
+					},
+					&appsv1.ReplicaSet{
+						ObjectMeta: metav1.ObjectMeta{Name: "scaled-down", Namespace: "default"},
+						Spec:       appsv1.ReplicaSetSpec{Replicas: ptr.To[int32](0), Template: template},
+					},
+					&appsv1.ReplicaSet{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:            "web-1234",
+							Namespace:       "default",
+							OwnerReferences: []metav1.OwnerReference{owner},
+						},
+						Spec: appsv1.ReplicaSetSpec{Template: template},
+					},
+					&kbatchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{Name: "migration", Namespace: "default"},
+						Spec:       kbatchv1.JobSpec{Template: template},
+					},
+					&kbatchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default"},
+						Spec:       kbatchv1.JobSpec{Template: template},
+						Status: kbatchv1.JobStatus{
+							Conditions: []kbatchv1.JobCondition{
+								{Type: kbatchv1.JobComplete, Status: corev1.ConditionTrue},
+							},
+						},
+					},
+					&kbatchv1.Job{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "backup-28000000",
+							Namespace: "default",
+							OwnerReferences: []metav1.OwnerReference{
+								{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", UID: "backup-uid", Controller: ptr.To(true)},
+							},
+						},
+						Spec: kbatchv1.JobSpec{Template: template},
+					},
+				).Build(),
+				Scheme: scheme,
+			}
+			pods, err := reconciler.fetchWorkloadPods(context.Background(), "default")
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, pod := range pods {
+				names = append(names, pod.Name)
+			}
+			Expect(names).To(ConsistOf("standalone", "migration"))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expression compiles and evaluates the CEL expressions of
+// ImmutableImages that decide whether a reference locks its secret.
+package expression
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Variables available to the expressions, the pod and container are the
+// unstructured form of their Kubernetes object.
+const (
+	PodVariable       = "pod"
+	ContainerVariable = "container"
+	ReferenceVariable = "reference"
+)
+
+// CostLimit bounds the cost of a single evaluation, expressions run for every
+// reference of every pod on each reconcile. It matches the per call limit of
+// Kubernetes validation rules.
+const CostLimit uint64 = 1000000
+
+var (
+	envOnce sync.Once
+	env     *cel.Env
+	envErr  error
+)
+
+// newEnv returns the CEL environment shared by every expression.
+func newEnv() (*cel.Env, error) {
+	envOnce.Do(func() {
+		object := cel.MapType(cel.StringType, cel.DynType)
+		env, envErr = cel.NewEnv(
+			cel.Variable(PodVariable, object),
+			cel.Variable(ContainerVariable, object),
+			cel.Variable(ReferenceVariable, object),
+		)
+	})
+	return env, envErr
+}
+
+// Compile parses and type-checks the expression, which must evaluate to a
+// bool. Expressions of the dyn type, e.g. reference.readOnly, are checked
+// when they are evaluated.
+func Compile(expression string) (cel.Program, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("must evaluate to bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(CostLimit))
+}
+
+// compiled caches the programs of expressions that compiled successfully.
+var compiled sync.Map
+
+// Evaluate runs the expression against the variables and reports whether it
+// evaluated to true.
+func Evaluate(expression string, vars map[string]any) (bool, error) {
+	cached, ok := compiled.Load(expression)
+	if !ok {
+		program, err := Compile(expression)
+		if err != nil {
+			return false, err
+		}
+		cached, _ = compiled.LoadOrStore(expression, program)
+	}
+	out, _, err := cached.(cel.Program).Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	holds, ok := out.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("must evaluate to bool, not %s", out.Type())
+	}
+	return bool(holds), nil
+}
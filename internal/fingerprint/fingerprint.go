@@ -0,0 +1,71 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fingerprint computes the content hashes pinning the data of locked
+// secrets, so that a deleted secret can only be recreated as it was.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+)
+
+// Prefix is the algorithm prefix of every fingerprint.
+const Prefix = "sha256:"
+
+// Data returns the SHA-256 fingerprint of the data of a secret. Keys are
+// hashed in order and every key and value is length-prefixed, so that the
+// fingerprint does not depend on map ordering and no two data collide by
+// shifting bytes between a key and its value.
+func Data(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	var length [8]byte
+	for _, key := range keys {
+		binary.BigEndian.PutUint64(length[:], uint64(len(key)))
+		hash.Write(length[:])
+		hash.Write([]byte(key))
+		binary.BigEndian.PutUint64(length[:], uint64(len(data[key])))
+		hash.Write(length[:])
+		hash.Write(data[key])
+	}
+	return Prefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// Lock returns the fingerprint of the data pinned by a lock. At key
+// granularity only the consumed keys are pinned, the other keys can be
+// changed without breaking the pin.
+func Lock(granularity batchv1.LockGranularity, lock *batchv1.SecretLock, data map[string][]byte) string {
+	if granularity != batchv1.LockGranularityKey || lock.AllKeys {
+		return Data(data)
+	}
+	locked := make(map[string][]byte, len(lock.Keys))
+	for _, key := range lock.Keys {
+		if value, found := data[key]; found {
+			locked[key] = value
+		}
+	}
+	return Data(locked)
+}
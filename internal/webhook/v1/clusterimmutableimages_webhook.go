@@ -0,0 +1,91 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var clusterimmutableimageslog = logf.Log.WithName("clusterimmutableimages-resource")
+
+// SetupClusterImmutableImagesWebhookWithManager registers the webhook for ClusterImmutableImages in the manager.
+func SetupClusterImmutableImagesWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&batchv1.ClusterImmutableImages{}).
+		WithValidator(&ClusterImmutableImagesCustomValidator{}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-batch-github-com-v1-clusterimmutableimages,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch.github.com,resources=clusterimmutableimages,verbs=create;update,versions=v1,name=vclusterimmutableimages-v1.kb.io,admissionReviewVersions=v1
+
+// ClusterImmutableImagesCustomValidator struct is responsible for validating the ClusterImmutableImages resource
+// when it is created, updated, or deleted.
+type ClusterImmutableImagesCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ClusterImmutableImagesCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ClusterImmutableImages.
+func (v *ClusterImmutableImagesCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	images, ok := obj.(*batchv1.ClusterImmutableImages)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterImmutableImages object but got %T", obj)
+	}
+	clusterimmutableimageslog.Info("Validation for ClusterImmutableImages upon creation", "name", images.GetName())
+
+	return deprecationWarnings(&images.Spec.ImmutableImagesSpec, field.NewPath("spec")), validateClusterImmutableImages(images)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ClusterImmutableImages.
+func (v *ClusterImmutableImagesCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	images, ok := newObj.(*batchv1.ClusterImmutableImages)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterImmutableImages object for the newObj but got %T", newObj)
+	}
+	clusterimmutableimageslog.Info("Validation for ClusterImmutableImages upon update", "name", images.GetName())
+
+	return deprecationWarnings(&images.Spec.ImmutableImagesSpec, field.NewPath("spec")), validateClusterImmutableImages(images)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ClusterImmutableImages.
+func (v *ClusterImmutableImagesCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateClusterImmutableImages checks the parts of the spec that the CRD schema cannot.
+func validateClusterImmutableImages(images *batchv1.ClusterImmutableImages) error {
+	allErrs := validateImmutableImagesSpec(&images.Spec.ImmutableImagesSpec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: batchv1.GroupVersion.Group, Kind: "ClusterImmutableImages"},
+		images.Name, allErrs)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ClusterImmutableImages Webhook", func() {
+	var (
+		obj       *batchv1.ClusterImmutableImages
+		validator ClusterImmutableImagesCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &batchv1.ClusterImmutableImages{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-imagelist",
+			},
+			Spec: batchv1.ClusterImmutableImagesSpec{
+				ImmutableImagesSpec: batchv1.ImmutableImagesSpec{
+					Images: []string{"nginx:1.24"},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"environment": "production"},
+					},
+				},
+			},
+		}
+		validator = ClusterImmutableImagesCustomValidator{}
+	})
+
+	Context("When creating ClusterImmutableImages under Validating Webhook", func() {
+		It("Should admit a valid namespace selector", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
+		})
+
+		It("Should deny an invalid namespace selector", func() {
+			obj.Spec.NamespaceSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+				{Key: "environment", Operator: metav1.LabelSelectorOpExists, Values: []string{"production"}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+	})
+})
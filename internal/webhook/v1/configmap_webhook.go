@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var configmaplog = logf.Log.WithName("configmap-resource")
+
+// SetupConfigMapWebhookWithManager registers the webhook for ConfigMap in the manager.
+func SetupConfigMapWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.ConfigMap{}).
+		WithValidator(&ConfigMapCustomValidator{
+			client: mgr.GetClient(),
+		}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate--v1-configmap,mutating=false,failurePolicy=fail,sideEffects=NoneOnDryRun,groups="",resources=configmaps,verbs=update,versions=v1,name=vconfigmap-v1.kb.io,admissionReviewVersions=v1
+
+// ConfigMapCustomValidator struct is responsible for validating the ConfigMap resource
+// when it is created, updated, or deleted.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type ConfigMapCustomValidator struct {
+	client client.Client
+}
+
+var _ webhook.CustomValidator = &ConfigMapCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigMap object but got %T", obj)
+	}
+	configmaplog.Info("Validation for ConfigMap upon creation", "name", configMap.GetName())
+
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	configMap, ok := newObj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigMap object for the newObj but got %T", newObj)
+	}
+	configmaplog.Info("Validation for ConfigMap upon update", "name", configMap.GetName())
+
+	oldConfigMap, ok := oldObj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigMap object for the oldObj but got %T", oldObj)
+	}
+	// Labels, annotations, finalizers and ownerReferences are maintained by
+	// routine tooling and do not change what the consumers read
+	if len(changedConfigMapKeys(oldConfigMap, configMap)) == 0 {
+		return nil, nil
+	}
+
+	immutableImagesList := &batchv1.ImmutableImagesList{}
+
+	// Only the locks of the namespace of the configmap apply to it
+	if err := v.client.List(ctx, immutableImagesList, client.InNamespace(configMap.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list immutableImages: %w", err)
+	}
+
+	decision := &lockDecision{}
+	key := batchv1.NamespacedName{Namespace: configMap.Namespace, Name: configMap.Name}
+	for _, images := range immutableImagesList.Items {
+		if slices.Contains(images.Status.ImmutableConfigMaps, key) {
+			denied := fmt.Errorf("attempting to update immutable configmap %s", key)
+			if err := enforce(ctx, &images, configMapEnforcementMode(&images.Spec, &images.Status.LockStatus, key),
+				"ConfigMap", configMap, denied, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	clusterImagesList := &batchv1.ClusterImmutableImagesList{}
+	if err := v.client.List(ctx, clusterImagesList); err != nil {
+		return nil, fmt.Errorf("failed to list clusterImmutableImages: %w", err)
+	}
+	for _, images := range clusterImagesList.Items {
+		locks, found := images.Status.Namespace(configMap.Namespace)
+		if found && slices.Contains(locks.ImmutableConfigMaps, key) {
+			denied := fmt.Errorf("attempting to update immutable configmap %s", key)
+			if err := enforce(ctx, &images, configMapEnforcementMode(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, key),
+				"ConfigMap", configMap, denied, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	recordAuditViolations(ctx, v.client, decision)
+	return decision.warnings, nil
+}
+
+// changedConfigMapKeys returns the keys of data and binaryData that were
+// added, removed or edited between the two configmaps.
+func changedConfigMapKeys(oldConfigMap, newConfigMap *corev1.ConfigMap) []string {
+	keys := sets.New[string]()
+	for _, configMap := range []*corev1.ConfigMap{oldConfigMap, newConfigMap} {
+		for key := range configMap.Data {
+			keys.Insert(key)
+		}
+		for key := range configMap.BinaryData {
+			keys.Insert(key)
+		}
+	}
+	var changed []string
+	for key := range keys {
+		oldValue, oldFound := oldConfigMap.Data[key]
+		newValue, newFound := newConfigMap.Data[key]
+		oldBinary, oldBinaryFound := oldConfigMap.BinaryData[key]
+		newBinary, newBinaryFound := newConfigMap.BinaryData[key]
+		if oldFound != newFound || oldValue != newValue ||
+			oldBinaryFound != newBinaryFound || !bytes.Equal(oldBinary, newBinary) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// configMapEnforcementMode returns the enforcement mode of the lock of a CR on
+// the configmap. ConfigMaps without a lock follow the strictest mode of the rules.
+func configMapEnforcementMode(spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus, key batchv1.NamespacedName) batchv1.EnforcementMode {
+	idx := slices.IndexFunc(locks.LockedConfigMaps, func(lock batchv1.ConfigMapLock) bool {
+		return lock.Namespace == key.Namespace && lock.Name == key.Name
+	})
+	if idx >= 0 {
+		return batchv1.StrictestEnforcementMode(locks.LockedConfigMaps[idx].EnforcementMode)
+	}
+	return spec.RulesEnforcementMode()
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, fmt.Errorf("expected a ConfigMap object but got %T", obj)
+	}
+	configmaplog.Info("Validation for ConfigMap upon deletion", "name", configMap.GetName())
+
+	return nil, nil
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("ConfigMap Webhook", func() {
+	var (
+		newObj    *corev1.ConfigMap
+		oldObj    *corev1.ConfigMap
+		validator ConfigMapCustomValidator
+	)
+
+	BeforeEach(func() {
+		oldObj = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "configmap-1",
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				"feature-flag": "off",
+			},
+		}
+		newObj = oldObj.DeepCopy()
+		newObj.Data["feature-flag"] = "on"
+		validator = ConfigMapCustomValidator{
+			client: k8sClient,
+		}
+		imageList := &batchv1.ImmutableImages{}
+		typeNamespacedName := types.NamespacedName{
+			Name:      "imagelist-configmap",
+			Namespace: "default",
+		}
+		ctx := context.Background()
+		By("creating the custom resource for the Kind ImmutableImages")
+		err := k8sClient.Get(ctx, typeNamespacedName, imageList)
+		if err != nil && errors.IsNotFound(err) {
+			imageList := &batchv1.ImmutableImages{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "imagelist-configmap",
+					Namespace: "default",
+				},
+				Spec: batchv1.ImmutableImagesSpec{
+					Images: []string{
+						"alpine:latest",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, imageList)).To(Succeed())
+			imageList.Status.ImmutableConfigMaps = []batchv1.NamespacedName{
+				{Namespace: "default", Name: "configmap-2"},
+			}
+			Expect(k8sClient.Status().Update(ctx, imageList)).To(Succeed())
+		}
+	})
+
+	Context("When updating ConfigMap under Validating Webhook", func() {
+		It("Should update when configmap is not in immutable list", func() {
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to update the configmap")
+		})
+
+		It("Should fail for update in immutable configmap", func() {
+			oldObj.Name = "configmap-2"
+			newObj.Name = "configmap-2"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for updating immutable configmap")
+		})
+
+		It("Should allow metadata changes to an immutable configmap", func() {
+			oldObj.Name = "configmap-2"
+			newObj = oldObj.DeepCopy()
+			newObj.Labels = map[string]string{"app.kubernetes.io/managed-by": "kubectl"}
+			newObj.Annotations = map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"}
+			newObj.Finalizers = []string{"example.com/cleanup"}
+			newObj.OwnerReferences = []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "owner", UID: "owner-uid"},
+			}
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to allow metadata changes")
+		})
+
+		It("Should fail for binaryData changes to an immutable configmap", func() {
+			oldObj.Name = "configmap-2"
+			newObj = oldObj.DeepCopy()
+			newObj.BinaryData = map[string][]byte{"logo.png": {0x89, 0x50}}
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for adding binary data")
+		})
+
+		It("Should update a configmap of the same name in another namespace", func() {
+			oldObj.Name = "configmap-2"
+			newObj.Name = "configmap-2"
+			oldObj.Namespace = "team-b"
+			newObj.Namespace = "team-b"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to update the unrelated configmap")
+		})
+	})
+
+	Context("When updating a locked ConfigMap in Warn mode", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			warnList := &batchv1.ImmutableImages{}
+			warnLookupKey := types.NamespacedName{
+				Name:      "imagelist-configmap-warn",
+				Namespace: "default",
+			}
+			err := k8sClient.Get(ctx, warnLookupKey, warnList)
+			if err != nil && errors.IsNotFound(err) {
+				warnList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-configmap-warn",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						EnforcementMode: batchv1.EnforcementModeWarn,
+					},
+				}
+				Expect(k8sClient.Create(ctx, warnList)).To(Succeed())
+				warnList.Status.ImmutableConfigMaps = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "configmap-warn"},
+				}
+				Expect(k8sClient.Status().Update(ctx, warnList)).To(Succeed())
+			}
+			oldObj.Name = "configmap-warn"
+			newObj.Name = "configmap-warn"
+		})
+
+		It("Should allow the update with a warning", func() {
+			warnings, err := validator.ValidateUpdate(ctx, oldObj, newObj)
+			Expect(err).NotTo(HaveOccurred(), "Expected validation to allow the update")
+			Expect(warnings).To(ConsistOf(ContainSubstring("default/configmap-warn")))
+		})
+	})
+
+	Context("When updating a ConfigMap locked by a Warn rule of a CR in Enforce mode", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			ruleList := &batchv1.ImmutableImages{}
+			ruleLookupKey := types.NamespacedName{
+				Name:      "imagelist-configmap-rule",
+				Namespace: "default",
+			}
+			err := k8sClient.Get(ctx, ruleLookupKey, ruleList)
+			if err != nil && errors.IsNotFound(err) {
+				ruleList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-configmap-rule",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						Rules: []batchv1.ImageRule{
+							{
+								Name:            "settings",
+								Images:          []string{"settings:1.0"},
+								EnforcementMode: batchv1.EnforcementModeWarn,
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, ruleList)).To(Succeed())
+				ruleList.Status.ImmutableConfigMaps = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "configmap-rule"},
+				}
+				ruleList.Status.LockedConfigMaps = []batchv1.ConfigMapLock{
+					{Namespace: "default", Name: "configmap-rule", EnforcementMode: batchv1.EnforcementModeWarn},
+				}
+				Expect(k8sClient.Status().Update(ctx, ruleList)).To(Succeed())
+			}
+			oldObj.Name = "configmap-rule"
+			newObj.Name = "configmap-rule"
+		})
+
+		It("Should follow the enforcement mode of the lock", func() {
+			warnings, err := validator.ValidateUpdate(ctx, oldObj, newObj)
+			Expect(err).NotTo(HaveOccurred(), "Expected validation to allow the update")
+			Expect(warnings).To(ConsistOf(ContainSubstring("default/configmap-rule")))
+		})
+	})
+
+	Context("When a dry run updates a locked ConfigMap in Audit mode", func() {
+		auditLookupKey := types.NamespacedName{
+			Name:      "imagelist-configmap-audit",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			auditList := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, auditLookupKey, auditList)
+			if err != nil && errors.IsNotFound(err) {
+				auditList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-configmap-audit",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						EnforcementMode: batchv1.EnforcementModeAudit,
+					},
+				}
+				Expect(k8sClient.Create(ctx, auditList)).To(Succeed())
+				auditList.Status.ImmutableConfigMaps = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "configmap-audit"},
+				}
+				Expect(k8sClient.Status().Update(ctx, auditList)).To(Succeed())
+			}
+			oldObj.Name = "configmap-audit"
+			newObj.Name = "configmap-audit"
+		})
+
+		It("Should allow the update without recording it", func() {
+			dryRun := true
+			dryRunCtx := admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					DryRun:    &dryRun,
+				},
+			})
+			Expect(validator.ValidateUpdate(dryRunCtx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to allow the update")
+			auditList := &batchv1.ImmutableImages{}
+			Expect(k8sClient.Get(ctx, auditLookupKey, auditList)).To(Succeed())
+			Expect(auditList.Status.AuditViolations).To(BeEmpty())
+		})
+	})
+
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// enforcementlog logs the changes allowed despite a lock.
+var enforcementlog = logf.Log.WithName("enforcement")
+
+// lockDecision collects what the locks of every CR decided on a change.
+type lockDecision struct {
+	// warnings are returned to the client when the change is allowed
+	warnings admission.Warnings
+	// exemptions are the denials waived by break-glass, they are only
+	// recorded once every CR allowed the change
+	exemptions []exemption
+	// violations are the denials allowed in Audit mode, they are only
+	// recorded once every CR allowed the change
+	violations []auditRecord
+}
+
+// auditRecord is a violation to record in the status of the CR holding the lock.
+type auditRecord struct {
+	images    client.Object
+	violation batchv1.AuditViolation
+}
+
+// exemption is a change denied by the locks of a CR and waived by its breakGlass.
+type exemption struct {
+	images client.Object
+	denied error
+}
+
+// enforce applies the enforcement mode of a lock to the change of obj it
+// denies: Enforce rejects it, Warn allows it with a warning and Audit allows
+// it and records it in the status of the CR once every CR allowed it.
+func enforce(ctx context.Context, images client.Object, mode batchv1.EnforcementMode, kind string, obj client.Object, denied error, decision *lockDecision) error {
+	switch mode {
+	case batchv1.EnforcementModeWarn:
+		decision.warnings = append(decision.warnings, denied.Error())
+		return nil
+	case batchv1.EnforcementModeAudit:
+		violation := batchv1.AuditViolation{
+			Time:      metav1.Now(),
+			Kind:      kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Message:   denied.Error(),
+		}
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			violation.Operation = string(req.Operation)
+			violation.User = req.UserInfo.Username
+		}
+		decision.violations = append(decision.violations, auditRecord{images: images, violation: violation})
+		return nil
+	default:
+		return denied
+	}
+}
+
+// recordAuditViolations records the audit violations of an allowed change in
+// the status of their CR, unless the request is a dry run.
+func recordAuditViolations(ctx context.Context, c client.Client, decision *lockDecision) {
+	// Dry runs must not have side effects
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.DryRun != nil && *req.DryRun {
+		return
+	}
+	for _, pending := range decision.violations {
+		// The change is allowed even when it cannot be recorded
+		if err := recordAuditViolation(ctx, c, pending.images, pending.violation); err != nil {
+			enforcementlog.Error(err, "Could not record audit violation",
+				"immutableImages", client.ObjectKeyFromObject(pending.images).String(), "violation", pending.violation.Message)
+		}
+	}
+}
+
+// recordAuditViolation appends the violation to the status of the CR,
+// retrying on conflicts with the controller.
+func recordAuditViolation(ctx context.Context, c client.Client, images client.Object, violation batchv1.AuditViolation) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch images.(type) {
+		case *batchv1.ImmutableImages:
+			latest := &batchv1.ImmutableImages{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(images), latest); err != nil {
+				return err
+			}
+			latest.Status.AuditViolations = batchv1.AppendAuditViolation(latest.Status.AuditViolations, violation)
+			return c.Status().Update(ctx, latest)
+		case *batchv1.ClusterImmutableImages:
+			latest := &batchv1.ClusterImmutableImages{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(images), latest); err != nil {
+				return err
+			}
+			latest.Status.AuditViolations = batchv1.AppendAuditViolation(latest.Status.AuditViolations, violation)
+			return c.Status().Update(ctx, latest)
+		default:
+			return fmt.Errorf("unexpected %T holding a lock", images)
+		}
+	})
+}
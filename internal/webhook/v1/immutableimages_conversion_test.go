@@ -0,0 +1,171 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	batchv2 "github.com/brongulus/secret-controller/api/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Conversion", func() {
+	var hub *batchv1.ImmutableImages
+
+	BeforeEach(func() {
+		hub = &batchv1.ImmutableImages{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "imagelist-conversion",
+				Namespace: "default",
+			},
+			Spec: batchv1.ImmutableImagesSpec{
+				Images: []string{"alpine:latest"},
+				Rules: []batchv1.ImageRule{
+					{
+						Name:          "payments",
+						ImagePatterns: []batchv1.ImagePattern{{Pattern: "registry.internal/payments/*"}},
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": "payments"},
+						},
+					},
+				},
+				Granularity:        batchv1.LockGranularityKey,
+				DeletionProtection: batchv1.DeletionProtectionDisabled,
+			},
+			Status: batchv1.ImmutableImagesStatus{
+				ObservedGeneration: 2,
+				LockedSecretCount:  1,
+				LockStatus: batchv1.LockStatus{
+					ImmutableSecrets: []batchv1.NamespacedName{{Namespace: "default", Name: "secret-1"}},
+				},
+			},
+		}
+	})
+
+	It("Should turn the top level criteria of v1 into the default rule of v2", func() {
+		spoke := &batchv2.ImmutableImages{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Rules).To(HaveLen(2))
+		Expect(spoke.Spec.Rules[0].Name).To(Equal(batchv1.DefaultRuleName))
+		Expect(spoke.Spec.Rules[0].Match.Images).To(Equal([]string{"alpine:latest"}))
+		Expect(spoke.Spec.Rules[1].Name).To(Equal("payments"))
+		Expect(spoke.Spec.Rules[1].Match.Patterns).To(Equal(hub.Spec.Rules[0].ImagePatterns))
+		Expect(spoke.Spec.Granularity).To(Equal(batchv1.LockGranularityKey))
+		Expect(spoke.Spec.DeletionProtection).To(Equal(batchv1.DeletionProtectionDisabled))
+		Expect(spoke.Status.Locks.ImmutableSecrets).To(Equal(hub.Status.ImmutableSecrets))
+	})
+
+	It("Should not add a default rule when v1 only has rules", func() {
+		hub.Spec.Images = nil
+		spoke := &batchv2.ImmutableImages{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Rules).To(HaveLen(1))
+		Expect(spoke.Spec.Rules[0].Name).To(Equal("payments"))
+	})
+
+	It("Should read the keys of the deprecated imageSecretMap as images", func() {
+		hub.Spec.ImageSecretsMap = map[string][]string{"nginx:1.24": {}, "alpine:latest": {}}
+		spoke := &batchv2.ImmutableImages{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Rules[0].Match.Images).To(Equal([]string{"alpine:latest", "nginx:1.24"}))
+	})
+
+	It("Should round-trip through v2", func() {
+		spoke := &batchv2.ImmutableImages{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		converted := &batchv1.ImmutableImages{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted).To(Equal(hub))
+	})
+	It("Should convert a rule with an empty match", func() {
+		spoke := &batchv2.ImmutableImages{
+			Spec: batchv2.ImmutableImagesSpec{
+				Rules: []batchv2.Rule{
+					{
+						Name: "labelled",
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"app": "critical"},
+						},
+					},
+				},
+			},
+		}
+		converted := &batchv1.ImmutableImages{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted.Spec.Rules).To(HaveLen(1))
+		Expect(converted.Spec.Rules[0].Images).To(BeEmpty())
+		Expect(converted.Spec.Rules[0].PodSelector).To(Equal(spoke.Spec.Rules[0].PodSelector))
+		roundTripped := &batchv2.ImmutableImages{}
+		Expect(roundTripped.ConvertFrom(converted)).To(Succeed())
+		Expect(roundTripped.Spec).To(Equal(spoke.Spec))
+	})
+
+	It("Should map a default rule that only sets the enforcement mode onto the v1 spec", func() {
+		spoke := &batchv2.ImmutableImages{
+			Spec: batchv2.ImmutableImagesSpec{
+				Rules: []batchv2.Rule{
+					{Name: batchv1.DefaultRuleName, EnforcementMode: batchv1.EnforcementModeAudit},
+					{Name: "payments", Match: batchv2.ImageMatch{Images: []string{"payments:1.0"}}},
+				},
+			},
+		}
+		converted := &batchv1.ImmutableImages{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		_, set := converted.Spec.DefaultRule()
+		Expect(set).To(BeFalse())
+		Expect(converted.Spec.EnforcementMode).To(Equal(batchv1.EnforcementModeAudit))
+		Expect(converted.Spec.RuleEnforcementMode(converted.Spec.Rules[0])).To(Equal(batchv1.EnforcementModeEnforce))
+		Expect(converted.Annotations).To(BeEmpty())
+		roundTripped := &batchv2.ImmutableImages{}
+		Expect(roundTripped.ConvertFrom(converted)).To(Succeed())
+		Expect(roundTripped.Spec.EnforcementMode).To(Equal(batchv1.EnforcementModeAudit))
+		Expect(roundTripped.Spec.Rules[0].EnforcementMode).To(Equal(batchv1.EnforcementModeEnforce))
+		Expect(roundTripped.Annotations).To(BeEmpty())
+	})
+
+	It("Should keep the enforcement mode of a default rule overriding the one of the spec", func() {
+		spoke := &batchv2.ImmutableImages{
+			Spec: batchv2.ImmutableImagesSpec{
+				EnforcementMode: batchv1.EnforcementModeAudit,
+				Rules: []batchv2.Rule{
+					{
+						Name:            batchv1.DefaultRuleName,
+						Match:           batchv2.ImageMatch{Images: []string{"alpine:latest"}},
+						EnforcementMode: batchv1.EnforcementModeEnforce,
+					},
+					{Name: "payments", Match: batchv2.ImageMatch{Images: []string{"payments:1.0"}}},
+				},
+			},
+		}
+		converted := &batchv1.ImmutableImages{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted.Spec.EnforcementMode).To(Equal(batchv1.EnforcementModeEnforce))
+		defaultRule, _ := converted.Spec.DefaultRule()
+		Expect(converted.Spec.RuleEnforcementMode(defaultRule)).To(Equal(batchv1.EnforcementModeEnforce))
+		Expect(converted.Spec.RuleEnforcementMode(converted.Spec.Rules[0])).To(Equal(batchv1.EnforcementModeAudit))
+		Expect(converted.Annotations).To(BeEmpty())
+		roundTripped := &batchv2.ImmutableImages{}
+		Expect(roundTripped.ConvertFrom(converted)).To(Succeed())
+		Expect(roundTripped.Spec.Rules).To(HaveLen(2))
+		Expect(roundTripped.Spec.EnforcementMode).To(Equal(batchv1.EnforcementModeEnforce))
+		Expect(roundTripped.Spec.Rules[0].Name).To(Equal(batchv1.DefaultRuleName))
+		Expect(roundTripped.Spec.Rules[0].EnforcementMode).To(BeEmpty())
+		Expect(roundTripped.Spec.Rules[1].EnforcementMode).To(Equal(batchv1.EnforcementModeAudit))
+	})
+})
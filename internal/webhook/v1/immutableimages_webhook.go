@@ -0,0 +1,211 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/blang/semver/v4"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/expression"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var immutableimageslog = logf.Log.WithName("immutableimages-resource")
+
+// SetupImmutableImagesWebhookWithManager registers the webhook for ImmutableImages in the manager.
+func SetupImmutableImagesWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&batchv1.ImmutableImages{}).
+		WithValidator(&ImmutableImagesCustomValidator{}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-batch-github-com-v1-immutableimages,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch.github.com,resources=immutableimages,verbs=create;update,versions=v1,name=vimmutableimages-v1.kb.io,admissionReviewVersions=v1
+
+// ImmutableImagesCustomValidator struct is responsible for validating the ImmutableImages resource
+// when it is created, updated, or deleted.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type ImmutableImagesCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ImmutableImagesCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ImmutableImages.
+func (v *ImmutableImagesCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	images, ok := obj.(*batchv1.ImmutableImages)
+	if !ok {
+		return nil, fmt.Errorf("expected a ImmutableImages object but got %T", obj)
+	}
+	immutableimageslog.Info("Validation for ImmutableImages upon creation", "name", images.GetName())
+
+	return deprecationWarnings(&images.Spec, field.NewPath("spec")), validateImmutableImages(images)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ImmutableImages.
+func (v *ImmutableImagesCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	images, ok := newObj.(*batchv1.ImmutableImages)
+	if !ok {
+		return nil, fmt.Errorf("expected a ImmutableImages object for the newObj but got %T", newObj)
+	}
+	immutableimageslog.Info("Validation for ImmutableImages upon update", "name", images.GetName())
+
+	return deprecationWarnings(&images.Spec, field.NewPath("spec")), validateImmutableImages(images)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ImmutableImages.
+func (v *ImmutableImagesCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateImmutableImages checks the parts of the spec that the CRD schema cannot.
+func validateImmutableImages(images *batchv1.ImmutableImages) error {
+	allErrs := validateImmutableImagesSpec(&images.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: batchv1.GroupVersion.Group, Kind: "ImmutableImages"},
+		images.Name, allErrs)
+}
+
+// deprecationWarnings warns about the deprecated fields set in the spec shared
+// by ImmutableImages and ClusterImmutableImages.
+func deprecationWarnings(spec *batchv1.ImmutableImagesSpec, specPath *field.Path) admission.Warnings {
+	var warnings admission.Warnings
+	if len(spec.ImageSecretsMap) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated, list the images in %s instead",
+			specPath.Child("imageSecretMap"), specPath.Child("images")))
+	}
+	return warnings
+}
+
+// validateImmutableImagesSpec checks the spec shared by ImmutableImages and
+// ClusterImmutableImages.
+func validateImmutableImagesSpec(spec *batchv1.ImmutableImagesSpec, specPath *field.Path) field.ErrorList {
+	defaultRule, _ := spec.DefaultRule()
+	allErrs := validateImageRule(&defaultRule, specPath)
+
+	rulesPath := specPath.Child("rules")
+	for i := range spec.Rules {
+		rule := &spec.Rules[i]
+		rulePath := rulesPath.Index(i)
+		if rule.Name == batchv1.DefaultRuleName {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("name"), rule.Name,
+				"is reserved for the top level criteria of the spec"))
+		}
+		allErrs = append(allErrs, validateImageRule(rule, rulePath)...)
+	}
+
+	return allErrs
+}
+
+// validateImageRule checks the criteria of a rule, the top level criteria of
+// the spec are checked as the default rule.
+func validateImageRule(rule *batchv1.ImageRule, rulePath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	patternsPath := rulePath.Child("imagePatterns")
+	for i, pattern := range rule.ImagePatterns {
+		patternPath := patternsPath.Index(i).Child("pattern")
+		switch pattern.Type {
+		case batchv1.ImagePatternRegex:
+			if _, err := regexp.Compile(pattern.Pattern); err != nil {
+				allErrs = append(allErrs, field.Invalid(patternPath, pattern.Pattern, err.Error()))
+			}
+		default:
+			if _, err := path.Match(pattern.Pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(patternPath, pattern.Pattern, err.Error()))
+			}
+		}
+	}
+
+	matchersPath := rulePath.Child("imageMatchers")
+	for i, matcher := range rule.ImageMatchers {
+		matcherPath := matchersPath.Index(i)
+		if named, err := reference.ParseNormalizedNamed(matcher.Repository); err != nil {
+			allErrs = append(allErrs, field.Invalid(matcherPath.Child("repository"), matcher.Repository, err.Error()))
+		} else if !reference.IsNameOnly(named) {
+			allErrs = append(allErrs, field.Invalid(matcherPath.Child("repository"), matcher.Repository,
+				"must not contain a tag or a digest, use the tag and digest fields instead"))
+		}
+		if matcher.Tag != "" && matcher.Versions != "" {
+			allErrs = append(allErrs, field.Forbidden(matcherPath.Child("versions"), "may not be set together with tag"))
+		}
+		if matcher.Versions != "" {
+			if _, err := semver.ParseRange(matcher.Versions); err != nil {
+				allErrs = append(allErrs, field.Invalid(matcherPath.Child("versions"), matcher.Versions, err.Error()))
+			}
+		}
+		if matcher.Digest != "" {
+			if _, err := digest.Parse(matcher.Digest); err != nil {
+				allErrs = append(allErrs, field.Invalid(matcherPath.Child("digest"), matcher.Digest, err.Error()))
+			}
+		}
+	}
+
+	selectorOpts := metav1validation.LabelSelectorValidationOptions{}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+		rule.PodSelector, selectorOpts, rulePath.Child("podSelector"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+		rule.NamespaceSelector, selectorOpts, rulePath.Child("namespaceSelector"))...)
+
+	expressionsPath := rulePath.Child("lockExpressions")
+	for i, expr := range rule.LockExpressions {
+		if _, err := expression.Compile(expr); err != nil {
+			allErrs = append(allErrs, field.Invalid(expressionsPath.Index(i), expr, err.Error()))
+		}
+	}
+
+	if exclusions := rule.Exclusions; exclusions != nil {
+		exclusionsPath := rulePath.Child("exclusions")
+		for i, pattern := range exclusions.SecretNames {
+			if _, err := path.Match(pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(exclusionsPath.Child("secretNames").Index(i), pattern, err.Error()))
+			}
+		}
+		for i, pattern := range exclusions.ContainerNames {
+			if _, err := path.Match(pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(exclusionsPath.Child("containerNames").Index(i), pattern, err.Error()))
+			}
+		}
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+			exclusions.SecretSelector, selectorOpts, exclusionsPath.Child("secretSelector"))...)
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+			exclusions.PodSelector, selectorOpts, exclusionsPath.Child("podSelector"))...)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ImmutableImages Webhook", func() {
+	var (
+		obj       *batchv1.ImmutableImages
+		validator ImmutableImagesCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &batchv1.ImmutableImages{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "imagelist-patterns",
+				Namespace: "default",
+			},
+			Spec: batchv1.ImmutableImagesSpec{
+				ImagePatterns: []batchv1.ImagePattern{
+					{Pattern: "registry.internal/payments/*"},
+					{Pattern: `nginx:1\.2[4-5]\..*`, Type: batchv1.ImagePatternRegex},
+				},
+			},
+		}
+		validator = ImmutableImagesCustomValidator{}
+	})
+
+	Context("When creating ImmutableImages under Validating Webhook", func() {
+		It("Should admit valid image patterns", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
+		})
+
+		It("Should deny an invalid glob", func() {
+			obj.Spec.ImagePatterns = append(obj.Spec.ImagePatterns, batchv1.ImagePattern{Pattern: "registry.internal/[payments"})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny an invalid regular expression", func() {
+			oldObj := obj.DeepCopy()
+			obj.Spec.ImagePatterns = append(obj.Spec.ImagePatterns,
+				batchv1.ImagePattern{Pattern: "nginx:(1.24", Type: batchv1.ImagePatternRegex})
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit a structured image matcher", func() {
+			obj.Spec.ImageMatchers = []batchv1.ImageMatcher{
+				{Repository: "nginx", Versions: ">=1.24.0 <1.26.0"},
+				{Repository: "registry.internal/auth"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
+		})
+
+		It("Should deny an invalid image matcher", func() {
+			obj.Spec.ImageMatchers = []batchv1.ImageMatcher{
+				{Repository: "nginx:1.25", Versions: "between 1.24 and 1.26"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny an invalid pod selector", func() {
+			obj.Spec.PodSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn},
+				},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny an invalid exclusion pattern", func() {
+			obj.Spec.Exclusions = &batchv1.Exclusions{
+				SecretNames: []string{"*-tls", "cert-[manager"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit a lock expression", func() {
+			obj.Spec.LockExpressions = []string{
+				`container.image.startsWith('registry.internal/') && !('skip-lock' in pod.metadata.annotations)`,
+				`reference.source != 'Env'`,
+				`reference.readOnly`,
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
+		})
+
+		It("Should deny a lock expression that does not type-check", func() {
+			obj.Spec.LockExpressions = []string{`reference.secretName + 1`}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.LockExpressions = []string{`image == 'nginx'`}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.LockExpressions = []string{`reference.secretName.size()`}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit named rules", func() {
+			obj.Spec.Rules = []batchv1.ImageRule{
+				{Name: "payments", ImagePatterns: []batchv1.ImagePattern{{Pattern: "registry.internal/payments/*"}}},
+				{Name: "labelled", PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "critical"}}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
+		})
+
+		It("Should deny an invalid rule", func() {
+			obj.Spec.Rules = []batchv1.ImageRule{
+				{Name: "payments", ImagePatterns: []batchv1.ImagePattern{{Pattern: "registry.internal/[payments"}}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should warn about the deprecated imageSecretMap", func() {
+			obj.Spec.ImageSecretsMap = map[string][]string{"alpine:latest": {}}
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("spec.imageSecretMap is deprecated")))
+		})
+
+		It("Should deny a rule named after the top level criteria", func() {
+			obj.Spec.Rules = []batchv1.ImageRule{
+				{Name: batchv1.DefaultRuleName, Images: []string{"alpine:latest"}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+	})
+})
@@ -17,13 +17,22 @@ limitations under the License.
 package v1
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"slices"
 
 	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/fingerprint"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -39,17 +48,18 @@ var secretlog = logf.Log.WithName("secret-resource")
 func SetupSecretWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Secret{}).
 		WithValidator(&SecretCustomValidator{
-			client: mgr.GetClient(),
+			client:   mgr.GetClient(),
+			recorder: mgr.GetEventRecorderFor("secret-webhook"),
 		}).
 		Complete()
 }
 
 // TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 
-// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 // NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
 // Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
-// +kubebuilder:webhook:path=/validate--v1-secret,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=secrets,verbs=update,versions=v1,name=vsecret-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:webhook:path=/validate--v1-secret,mutating=false,failurePolicy=fail,sideEffects=NoneOnDryRun,groups="",resources=secrets,verbs=create;update;delete,versions=v1,name=vsecret-v1.kb.io,admissionReviewVersions=v1
 
 // SecretCustomValidator struct is responsible for validating the Secret resource
 // when it is created, updated, or deleted.
@@ -59,6 +69,8 @@ func SetupSecretWebhookWithManager(mgr ctrl.Manager) error {
 type SecretCustomValidator struct {
 	//TODO(user): Add more fields as needed for validation
 	client client.Client
+	// recorder records the break-glass exemptions, they are only logged when nil
+	recorder record.EventRecorder
 }
 
 var _ webhook.CustomValidator = &SecretCustomValidator{}
@@ -71,9 +83,86 @@ func (v *SecretCustomValidator) ValidateCreate(ctx context.Context, obj runtime.
 	}
 	secretlog.Info("Validation for Secret upon creation", "name", secret.GetName())
 
-	// TODO(user): fill in your validation logic upon object creation.
+	// DONE: A locked secret that went missing can only be recreated with the
+	// data it was pinned with
+	decision := &lockDecision{}
+	immutableImagesList := &batchv1.ImmutableImagesList{}
+	if err := v.client.List(ctx, immutableImagesList, client.InNamespace(secret.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list immutableImages: %w", err)
+	}
+	for _, images := range immutableImagesList.Items {
+		if err := checkSecretContent(&images.Spec, &images.Status.LockStatus, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec, &images.Status.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	clusterImagesList := &batchv1.ClusterImmutableImagesList{}
+	if err := v.client.List(ctx, clusterImagesList); err != nil {
+		return nil, fmt.Errorf("failed to list clusterImmutableImages: %w", err)
+	}
+	for _, images := range clusterImagesList.Items {
+		locks, found := images.Status.Namespace(secret.Namespace)
+		if !found {
+			continue
+		}
+		if err := checkSecretContent(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec.ImmutableImagesSpec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	v.recordBreakGlass(ctx, secret, decision)
+	recordAuditViolations(ctx, v.client, decision)
+	return decision.warnings, nil
+}
 
-	return nil, nil
+// checkSecretContent returns an error when the secret is still referenced by
+// a lock of the CR and its data differs from the pinned fingerprint.
+func checkSecretContent(spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus, secret *corev1.Secret) error {
+	lock, found := secretLock(locks, secret)
+	if !found {
+		return nil
+	}
+	if lock.ContentHash == "" || len(lock.Consumers) == 0 {
+		return nil
+	}
+	if fingerprint.Lock(spec.Granularity, lock, secretData(secret)) != lock.ContentHash {
+		return fmt.Errorf("attempting to recreate immutable secret %s/%s with different content",
+			secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// secretLock returns the lock of the CR on the secret, consumed by containers
+// or pulled with as an image pull secret.
+func secretLock(locks *batchv1.LockStatus, secret *corev1.Secret) (*batchv1.SecretLock, bool) {
+	for _, secretLocks := range [][]batchv1.SecretLock{locks.LockedSecrets, locks.LockedPullSecrets} {
+		idx := slices.IndexFunc(secretLocks, func(lock batchv1.SecretLock) bool {
+			return lock.Namespace == secret.Namespace && lock.Name == secret.Name
+		})
+		if idx >= 0 {
+			return &secretLocks[idx], true
+		}
+	}
+	return nil, false
+}
+
+// secretData returns the data of the secret with stringData merged in, as
+// the apiserver stores it.
+func secretData(secret *corev1.Secret) map[string][]byte {
+	data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	for key, value := range secret.StringData {
+		data[key] = []byte(value)
+	}
+	return data
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Secret.
@@ -90,22 +179,252 @@ func (v *SecretCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newO
 	// However reconcile looks at the map to update the blacklisted secret list on deletion/updation in CR
 
 	// DONE: Get CR list, check if secret is contained in any of their status
+	decision := &lockDecision{}
 	immutableImagesList := &batchv1.ImmutableImagesList{}
 
-	if err := v.client.List(ctx, immutableImagesList); err != nil {
+	// Only the locks of the namespace of the secret apply to it
+	if err := v.client.List(ctx, immutableImagesList, client.InNamespace(secret.Namespace)); err != nil {
 		return nil, fmt.Errorf("failed to list immutableImages: %w", err)
 	}
 
 	for _, images := range immutableImagesList.Items {
-		fmt.Printf("SecretList: %v, key: %v\n", images.Spec.ImmutableSecrets, secret.Name)
-		if slices.Contains(images.Spec.ImmutableSecrets, secret.Name) {
-			return nil, fmt.Errorf("attempting to update immutable secret %s",
-				secret.Name)
+		fmt.Printf("SecretList: %v, key: %v\n", images.Status.ImmutableSecrets, secret.Name)
+		if err := checkSecretLocks(&images.Spec, &images.Status.LockStatus, oldObj, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec, &images.Status.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
 		}
 	}
 
+	// DONE: Cluster-scoped CRs publish the locks of every selected namespace
+	clusterImagesList := &batchv1.ClusterImmutableImagesList{}
+	if err := v.client.List(ctx, clusterImagesList); err != nil {
+		return nil, fmt.Errorf("failed to list clusterImmutableImages: %w", err)
+	}
+	for _, images := range clusterImagesList.Items {
+		locks, found := images.Status.Namespace(secret.Namespace)
+		if !found {
+			continue
+		}
+		if err := checkSecretLocks(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, oldObj, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec.ImmutableImagesSpec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	v.recordBreakGlass(ctx, secret, decision)
+	recordAuditViolations(ctx, v.client, decision)
 	fmt.Println("Secret was allowed to be updated")
-	return nil, nil
+	return decision.warnings, nil
+}
+
+// admit decides what happens to a change denied by the locks of a CR, it is
+// allowed for break-glass requesters and otherwise follows the enforcement
+// mode of the lock. The returned error rejects the change.
+func (v *SecretCustomValidator) admit(ctx context.Context, images client.Object, spec *batchv1.ImmutableImagesSpec, mode batchv1.EnforcementMode, secret *corev1.Secret, denied error, decision *lockDecision) error {
+	if breakGlassExempts(ctx, spec.BreakGlass) {
+		decision.exemptions = append(decision.exemptions, exemption{images: images, denied: denied})
+		return nil
+	}
+	return enforce(ctx, images, mode, "Secret", secret, denied, decision)
+}
+
+// secretEnforcementMode returns the enforcement mode of the lock of a CR on
+// the secret, consumed by containers or pulled with. Secrets without a lock
+// follow the strictest mode of the rules.
+func secretEnforcementMode(spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus, secret *corev1.Secret) batchv1.EnforcementMode {
+	if lock, found := secretLock(locks, secret); found {
+		return batchv1.StrictestEnforcementMode(lock.EnforcementMode)
+	}
+	return spec.RulesEnforcementMode()
+}
+
+// breakGlassReason is the reason of the Events recording break-glass exemptions.
+const breakGlassReason = "BreakGlass"
+
+// breakGlassExempts reports whether the requester of the admission request
+// is exempted from the locks of the CR by its breakGlass.
+func breakGlassExempts(ctx context.Context, breakGlass *batchv1.BreakGlass) bool {
+	if breakGlass == nil {
+		return false
+	}
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && exemptsUser(breakGlass, req.UserInfo)
+}
+
+// recordBreakGlass records the break-glass exemptions of an allowed change
+// with an Event on the secret and on the CR, and an audit log entry, and pins
+// the new content of the secret, unless the request is a dry run. A change denied by another CR is not exempted, so
+// nothing is recorded before every CR allowed it.
+func (v *SecretCustomValidator) recordBreakGlass(ctx context.Context, secret *corev1.Secret, decision *lockDecision) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil || (req.DryRun != nil && *req.DryRun) {
+		return
+	}
+	for _, exempted := range decision.exemptions {
+		secretlog.Info("Break-glass exemption", "audit", true,
+			"uid", req.UID,
+			"operation", req.Operation,
+			"user", req.UserInfo.Username,
+			"groups", req.UserInfo.Groups,
+			"secret", client.ObjectKeyFromObject(secret).String(),
+			"immutableImages", client.ObjectKeyFromObject(exempted.images).String(),
+			"denied", exempted.denied.Error())
+		if v.recorder != nil {
+			message := fmt.Sprintf("%s by %s exempted by break-glass: %v", req.Operation, req.UserInfo.Username, exempted.denied)
+			v.recorder.Event(secret, corev1.EventTypeWarning, breakGlassReason, message)
+			v.recorder.Event(exempted.images, corev1.EventTypeWarning, breakGlassReason, message)
+		}
+		// The new content of a rotated secret is the one to keep, the old pin
+		// would otherwise report it as tampered with
+		if req.Operation == admissionv1.Delete {
+			continue
+		}
+		if err := pinSecretContent(ctx, v.client, exempted.images, secret); err != nil {
+			secretlog.Error(err, "Could not pin the content of the secret",
+				"secret", client.ObjectKeyFromObject(secret).String(),
+				"immutableImages", client.ObjectKeyFromObject(exempted.images).String())
+		}
+	}
+}
+
+// pinSecretContent replaces the fingerprint pinned by the lock of the CR on
+// the secret with the one of its new data, retrying on conflicts with the
+// controller.
+func pinSecretContent(ctx context.Context, c client.Client, images client.Object, secret *corev1.Secret) error {
+	pin := func(spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus) bool {
+		lock, found := secretLock(locks, secret)
+		if !found {
+			return false
+		}
+		lock.ContentHash = fingerprint.Lock(spec.Granularity, lock, secretData(secret))
+		lock.ContentMismatch = false
+		return true
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch images.(type) {
+		case *batchv1.ImmutableImages:
+			latest := &batchv1.ImmutableImages{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(images), latest); err != nil {
+				return err
+			}
+			if !pin(&latest.Spec, &latest.Status.LockStatus) {
+				return nil
+			}
+			return c.Status().Update(ctx, latest)
+		case *batchv1.ClusterImmutableImages:
+			latest := &batchv1.ClusterImmutableImages{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(images), latest); err != nil {
+				return err
+			}
+			locks, found := latest.Status.Namespace(secret.Namespace)
+			if !found || !pin(&latest.Spec.ImmutableImagesSpec, &locks.LockStatus) {
+				return nil
+			}
+			return c.Status().Update(ctx, latest)
+		default:
+			return fmt.Errorf("unexpected %T holding a lock", images)
+		}
+	})
+}
+
+// exemptsUser reports whether the user, one of its groups or its service
+// account is listed in breakGlass.
+func exemptsUser(breakGlass *batchv1.BreakGlass, user authenticationv1.UserInfo) bool {
+	if slices.Contains(breakGlass.Users, user.Username) {
+		return true
+	}
+	if slices.ContainsFunc(user.Groups, func(group string) bool {
+		return slices.Contains(breakGlass.Groups, group)
+	}) {
+		return true
+	}
+	return slices.ContainsFunc(breakGlass.ServiceAccounts, func(sa batchv1.ServiceAccountReference) bool {
+		return user.Username == "system:serviceaccount:"+sa.Namespace+":"+sa.Name
+	})
+}
+
+// checkSecretLocks returns an error when the update of the secret is denied by
+// the locks of a CR. Only changes to the data and type of a locked secret are
+// denied, its metadata stays editable.
+func checkSecretLocks(spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus, oldObj runtime.Object, secret *corev1.Secret) error {
+	key := batchv1.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+	locked := slices.Contains(locks.ImmutableSecrets, key)
+	pullLocked := slices.Contains(locks.ImmutablePullSecrets, key)
+	if !locked && !pullLocked {
+		return nil
+	}
+	oldSecret, ok := oldObj.(*corev1.Secret)
+	if !ok {
+		return fmt.Errorf("expected a Secret object for the oldObj but got %T", oldObj)
+	}
+	// Labels, annotations, finalizers and ownerReferences are maintained by
+	// routine tooling and do not change what the consumers read
+	if oldSecret.Type == secret.Type && len(changedKeys(oldSecret, secret)) == 0 {
+		return nil
+	}
+	// An image pull secret is pulled with as a whole, whatever keys its
+	// containers consume
+	if pullLocked {
+		return fmt.Errorf("attempting to update immutable image pull secret %s", key)
+	}
+	if spec.Granularity == batchv1.LockGranularityKey && oldSecret.Type == secret.Type &&
+		!changesLockedKeys(locks, oldSecret, secret) {
+		return nil
+	}
+	return fmt.Errorf("attempting to update immutable secret %s", key)
+}
+
+// secretValue returns the value of key in the secret, stringData takes
+// precedence over data as it does when the apiserver merges them, so
+// stringData encoding to the current data is not a change.
+func secretValue(secret *corev1.Secret, key string) ([]byte, bool) {
+	if value, found := secret.StringData[key]; found {
+		return []byte(value), true
+	}
+	value, found := secret.Data[key]
+	return value, found
+}
+
+// changedKeys returns the keys that were added, removed or edited between the two secrets.
+func changedKeys(oldSecret, newSecret *corev1.Secret) []string {
+	keys := sets.New[string]()
+	for _, secret := range []*corev1.Secret{oldSecret, newSecret} {
+		for key := range secret.Data {
+			keys.Insert(key)
+		}
+		for key := range secret.StringData {
+			keys.Insert(key)
+		}
+	}
+	var changed []string
+	for key := range keys {
+		oldValue, oldFound := secretValue(oldSecret, key)
+		newValue, newFound := secretValue(newSecret, key)
+		if oldFound != newFound || !bytes.Equal(oldValue, newValue) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// changesLockedKeys reports whether the update touches a key of the secret
+// that is consumed by a container, every key is locked when one of them
+// consumes the whole secret.
+func changesLockedKeys(locks *batchv1.LockStatus, oldSecret, newSecret *corev1.Secret) bool {
+	idx := slices.IndexFunc(locks.LockedSecrets, func(lock batchv1.SecretLock) bool {
+		return lock.Namespace == newSecret.Namespace && lock.Name == newSecret.Name
+	})
+	if idx < 0 || locks.LockedSecrets[idx].AllKeys {
+		return true
+	}
+	lockedKeys := locks.LockedSecrets[idx].Keys
+	return slices.ContainsFunc(changedKeys(oldSecret, newSecret), func(key string) bool {
+		return slices.Contains(lockedKeys, key)
+	})
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Secret.
@@ -116,7 +435,95 @@ func (v *SecretCustomValidator) ValidateDelete(ctx context.Context, obj runtime.
 	}
 	secretlog.Info("Validation for Secret upon deletion", "name", secret.GetName())
 
-	// TODO(user): fill in your validation logic upon object deletion.
+	// DONE: Deleting a locked secret and creating it again would replace its
+	// contents, refuse it while a running consumer remains
+	decision := &lockDecision{}
+	immutableImagesList := &batchv1.ImmutableImagesList{}
+	if err := v.client.List(ctx, immutableImagesList, client.InNamespace(secret.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list immutableImages: %w", err)
+	}
+	for _, images := range immutableImagesList.Items {
+		if err := v.checkSecretDeletion(ctx, &images.Spec, &images.Status.LockStatus, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec, &images.Status.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
 
-	return nil, nil
+	clusterImagesList := &batchv1.ClusterImmutableImagesList{}
+	if err := v.client.List(ctx, clusterImagesList); err != nil {
+		return nil, fmt.Errorf("failed to list clusterImmutableImages: %w", err)
+	}
+	for _, images := range clusterImagesList.Items {
+		locks, found := images.Status.Namespace(secret.Namespace)
+		if !found {
+			continue
+		}
+		if err := v.checkSecretDeletion(ctx, &images.Spec.ImmutableImagesSpec, &locks.LockStatus, secret); err != nil {
+			mode := secretEnforcementMode(&images.Spec.ImmutableImagesSpec, &locks.LockStatus, secret)
+			if err := v.admit(ctx, &images, &images.Spec.ImmutableImagesSpec, mode, secret, err, decision); err != nil {
+				return decision.warnings, err
+			}
+		}
+	}
+
+	v.recordBreakGlass(ctx, secret, decision)
+	recordAuditViolations(ctx, v.client, decision)
+	secretlog.V(1).Info("Secret was allowed to be deleted", "name", secret.GetName())
+	return decision.warnings, nil
+}
+
+// checkSecretDeletion returns an error when the secret is locked by the CR
+// and one of the consumers holding the lock is still a running pod. The pods
+// are looked up rather than trusted from the status, which may lag behind.
+func (v *SecretCustomValidator) checkSecretDeletion(ctx context.Context, spec *batchv1.ImmutableImagesSpec, locks *batchv1.LockStatus, secret *corev1.Secret) error {
+	if spec.DeletionProtection == batchv1.DeletionProtectionDisabled {
+		return nil
+	}
+	lock, found := secretLock(locks, secret)
+	if !found {
+		return nil
+	}
+	for _, consumer := range lock.Consumers {
+		running, err := v.runningPod(ctx, secret.Namespace, consumer.PodName, consumer.PodUID)
+		if err != nil {
+			return err
+		}
+		if running {
+			return fmt.Errorf("attempting to delete immutable secret %s/%s consumed by running pod %s",
+				secret.Namespace, secret.Name, consumer.PodName)
+		}
+	}
+	// Cluster CRs only keep the first consumers of a lock, and the names of
+	// the pods of all of them
+	for _, podName := range lock.ConsumerPods {
+		running, err := v.runningPod(ctx, secret.Namespace, podName, "")
+		if err != nil {
+			return err
+		}
+		if running {
+			return fmt.Errorf("attempting to delete immutable secret %s/%s consumed by running pod %s",
+				secret.Namespace, secret.Name, podName)
+		}
+	}
+	return nil
+}
+
+// runningPod reports whether the pod of a consumer is running and not being
+// deleted, a UID tells it apart from a pod recreated with the same name.
+func (v *SecretCustomValidator) runningPod(ctx context.Context, namespace, name string, uid types.UID) (bool, error) {
+	pod := &corev1.Pod{}
+	err := v.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pod)
+	if apierrors.IsNotFound(err) {
+		// Workloads consume secrets through their pods only
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get pod %s: %w", name, err)
+	}
+	if uid != "" && pod.UID != uid {
+		return false, nil
+	}
+	return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil, nil
 }
@@ -25,10 +25,16 @@ import (
 	. "github.com/onsi/gomega"
 
 	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	"github.com/brongulus/secret-controller/internal/fingerprint"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 var _ = Describe("Secret Webhook", func() {
@@ -80,16 +86,17 @@ var _ = Describe("Secret Webhook", func() {
 					Namespace: "default",
 				},
 				Spec: batchv1.ImmutableImagesSpec{
-					ImageSecretsMap: map[string][]string{
-						"alpine:latest": {},
-						"nginx:0.3":     {},
-					},
-					ImmutableSecrets: []string{
-						"secret-2",
+					Images: []string{
+						"alpine:latest",
+						"nginx:0.3",
 					},
 				},
 			}
 			Expect(k8sClient.Create(ctx, imageList)).To(Succeed())
+			imageList.Status.ImmutableSecrets = []batchv1.NamespacedName{
+				{Namespace: "default", Name: "secret-2"},
+			}
+			Expect(k8sClient.Status().Update(ctx, imageList)).To(Succeed())
 		}
 
 		Expect(validator).NotTo(BeNil(), "Expected validator to be initialized")
@@ -113,7 +120,7 @@ var _ = Describe("Secret Webhook", func() {
 			Eventually(func(g Gomega) {
 				g.Expect(k8sClient.Get(ctx, imageLookupKey, createdImage)).To(Succeed())
 			}, timeout, interval).Should(Succeed())
-			fmt.Printf("ImmutableSecretlist is %v\n", createdImage.Spec.ImmutableSecrets)
+			fmt.Printf("ImmutableSecretlist is %v\n", createdImage.Status.ImmutableSecrets)
 			fmt.Printf("Imagelist is %v\n", imageList)
 			By("simulating a valid update scenario")
 			newObj.StringData["password.txt"] = "newpassword"
@@ -132,10 +139,11 @@ var _ = Describe("Secret Webhook", func() {
 				g.Expect(k8sClient.Get(ctx, imageLookupKey, createdImage)).To(Succeed())
 			}, timeout, interval).Should(Succeed())
 
-			createdImage.Spec.ImmutableSecrets = append(createdImage.Spec.ImmutableSecrets, "secret-1")
-			Expect(k8sClient.Update(ctx, createdImage)).To(Succeed())
+			createdImage.Status.ImmutableSecrets = append(createdImage.Status.ImmutableSecrets,
+				batchv1.NamespacedName{Namespace: "default", Name: "secret-1"})
+			Expect(k8sClient.Status().Update(ctx, createdImage)).To(Succeed())
 
-			fmt.Printf("ImmutableSecretlist is %v\n", createdImage.Spec.ImmutableSecrets)
+			fmt.Printf("ImmutableSecretlist is %v\n", createdImage.Status.ImmutableSecrets)
 			By("simulating a invalid update scenario")
 			newObj.StringData["password.txt"] = "passupdatefail"
 			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
@@ -143,4 +151,694 @@ var _ = Describe("Secret Webhook", func() {
 		})
 	})
 
+	Context("When updating the metadata of a locked Secret", func() {
+		BeforeEach(func() {
+			oldObj.Name = "secret-2"
+			newObj.Name = "secret-2"
+			newObj.StringData["password.txt"] = oldObj.StringData["password.txt"]
+		})
+
+		It("Should allow label, annotation, finalizer and ownerReference changes", func() {
+			newObj.Labels = map[string]string{"app.kubernetes.io/managed-by": "Helm"}
+			newObj.Annotations = map[string]string{"argocd.argoproj.io/sync-wave": "1"}
+			newObj.Finalizers = []string{"example.com/cleanup"}
+			newObj.OwnerReferences = []metav1.OwnerReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "1234"},
+			}
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to allow a metadata-only update")
+		})
+
+		It("Should allow stringData that encodes to the current data", func() {
+			oldObj.Data = map[string][]byte{"password.txt": []byte("oldpass")}
+			oldObj.StringData = nil
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to treat identical stringData as a no-op")
+		})
+
+		It("Should fail for a change of type", func() {
+			newObj.Type = corev1.SecretTypeBasicAuth
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for changing the type of the locked secret")
+		})
+	})
+
+	Context("When updating same-named Secrets in different namespaces", func() {
+		BeforeEach(func() {
+			oldObj.Name = "secret-2"
+			newObj.Name = "secret-2"
+			newObj.StringData["password.txt"] = "passupdate"
+		})
+
+		It("Should fail for the secret in the namespace of the lock", func() {
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for updating the locked secret")
+		})
+
+		It("Should update the secret of the same name in another namespace", func() {
+			oldObj.Namespace = "team-b"
+			newObj.Namespace = "team-b"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to update the unrelated secret")
+		})
+	})
+
+	Context("When updating a Secret locked by a ClusterImmutableImages", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			clusterList := &batchv1.ClusterImmutableImages{}
+			clusterLookupKey := types.NamespacedName{Name: "imagelist-cluster"}
+			err := k8sClient.Get(ctx, clusterLookupKey, clusterList)
+			if err != nil && errors.IsNotFound(err) {
+				clusterList = &batchv1.ClusterImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "imagelist-cluster",
+					},
+					Spec: batchv1.ClusterImmutableImagesSpec{
+						ImmutableImagesSpec: batchv1.ImmutableImagesSpec{
+							Images: []string{
+								"alpine:latest",
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, clusterList)).To(Succeed())
+				clusterList.Status.Namespaces = []batchv1.NamespaceLockStatus{
+					{
+						Namespace: "default",
+						LockStatus: batchv1.LockStatus{
+							ImmutableSecrets: []batchv1.NamespacedName{
+								{Namespace: "default", Name: "secret-cluster"},
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Status().Update(ctx, clusterList)).To(Succeed())
+			}
+			oldObj.Name = "secret-cluster"
+			newObj.Name = "secret-cluster"
+			newObj.StringData["password.txt"] = "passupdate"
+		})
+
+		It("Should fail for the secret in a selected namespace", func() {
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for updating the locked secret")
+		})
+
+		It("Should update the secret in a namespace that is not selected", func() {
+			oldObj.Namespace = "team-b"
+			newObj.Namespace = "team-b"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to update the unrelated secret")
+		})
+	})
+
+	Context("When updating a Secret locked at key granularity", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			keyList := &batchv1.ImmutableImages{}
+			keyLookupKey := types.NamespacedName{
+				Name:      "imagelist-keys",
+				Namespace: "default",
+			}
+			err := k8sClient.Get(ctx, keyLookupKey, keyList)
+			if err != nil && errors.IsNotFound(err) {
+				keyList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-keys",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						Granularity: batchv1.LockGranularityKey,
+					},
+				}
+				Expect(k8sClient.Create(ctx, keyList)).To(Succeed())
+				keyList.Status.LockStatus = batchv1.LockStatus{
+					ImmutableSecrets: []batchv1.NamespacedName{
+						{Namespace: "default", Name: "secret-keys"},
+						{Namespace: "default", Name: "secret-keys-pulled"},
+					},
+					LockedSecrets: []batchv1.SecretLock{
+						{
+							Namespace: "default",
+							Name:      "secret-keys",
+							Keys:      []string{"password.txt"},
+						},
+						{
+							Namespace: "default",
+							Name:      "secret-keys-pulled",
+							Keys:      []string{"password.txt"},
+						},
+					},
+					ImmutablePullSecrets: []batchv1.NamespacedName{
+						{Namespace: "default", Name: "secret-keys-pulled"},
+					},
+				}
+				Expect(k8sClient.Status().Update(ctx, keyList)).To(Succeed())
+			}
+			oldObj.Name = "secret-keys"
+			newObj.Name = "secret-keys"
+			newObj.StringData["password.txt"] = oldObj.StringData["password.txt"]
+		})
+
+		It("Should allow changes to keys that are not consumed", func() {
+			newObj.StringData["username.txt"] = "admin"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeNil(),
+				"Expected validation to allow adding an unrelated key")
+		})
+
+		It("Should fail for changes to a consumed key", func() {
+			newObj.StringData["password.txt"] = "passupdatefail"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for updating a locked key")
+		})
+
+		It("Should fail for changes to any key of a secret that is also pulled with", func() {
+			oldObj.Name = "secret-keys-pulled"
+			newObj.Name = "secret-keys-pulled"
+			newObj.StringData["username.txt"] = "admin"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for updating an image pull secret")
+		})
+	})
+	Context("When deleting a locked Secret", func() {
+		var consumer *corev1.Pod
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			consumer = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "consumer-delete",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "consumer", Image: "alpine:latest"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, consumer)).To(Succeed())
+			consumer.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, consumer)).To(Succeed())
+
+			deleteList := &batchv1.ImmutableImages{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "imagelist-delete",
+					Namespace: "default",
+				},
+				Spec: batchv1.ImmutableImagesSpec{
+					Images: []string{
+						"alpine:latest",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deleteList)).To(Succeed())
+			deleteList.Status.LockStatus = batchv1.LockStatus{
+				ImmutableSecrets: []batchv1.NamespacedName{
+					{Namespace: "default", Name: "secret-delete"},
+				},
+				LockedSecrets: []batchv1.SecretLock{
+					{
+						Namespace: "default",
+						Name:      "secret-delete",
+						AllKeys:   true,
+						Consumers: []batchv1.SecretConsumer{
+							{PodName: consumer.Name, PodUID: consumer.UID, Container: "consumer", Image: "alpine:latest"},
+						},
+					},
+					{
+						Namespace: "default",
+						Name:      "secret-delete-truncated",
+						AllKeys:   true,
+						Consumers: []batchv1.SecretConsumer{
+							{PodName: "replica-gone", Container: "consumer", Image: "alpine:latest"},
+						},
+						ConsumerCount: 2,
+						ConsumerPods:  []string{consumer.Name, "replica-gone"},
+					},
+					{
+						Namespace: "default",
+						Name:      "secret-delete-unrelated",
+						AllKeys:   true,
+						Consumers: []batchv1.SecretConsumer{
+							{PodName: "replica-gone", Container: "consumer", Image: "alpine:latest"},
+						},
+						ConsumerCount: 2,
+						ConsumerPods:  []string{"replica-gone", "replica-gone-too"},
+					},
+				},
+				ImmutablePullSecrets: []batchv1.NamespacedName{
+					{Namespace: "default", Name: "pull-delete"},
+				},
+				LockedPullSecrets: []batchv1.SecretLock{
+					{
+						Namespace: "default",
+						Name:      "pull-delete",
+						AllKeys:   true,
+						Consumers: []batchv1.SecretConsumer{
+							{PodName: consumer.Name, PodUID: consumer.UID, Container: "consumer", Image: "alpine:latest",
+								Reference: batchv1.ReferenceKindImagePullSecret},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, deleteList)).To(Succeed())
+			oldObj.Name = "secret-delete"
+		})
+
+		AfterEach(func() {
+			ctx := context.Background()
+			deleteList := &batchv1.ImmutableImages{}
+			deleteLookupKey := types.NamespacedName{Name: "imagelist-delete", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, deleteLookupKey, deleteList)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, deleteList)).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, consumer))).To(Succeed())
+		})
+
+		It("Should fail while a running pod consumes the secret", func() {
+			Expect(validator.ValidateDelete(ctx, oldObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for deleting a consumed secret")
+		})
+
+		It("Should fail while a running pod pulls with the secret", func() {
+			oldObj.Name = "pull-delete"
+			Expect(validator.ValidateDelete(ctx, oldObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for deleting a pull secret in use")
+		})
+
+		It("Should fail while a running pod left out of the truncated consumers consumes the secret", func() {
+			oldObj.Name = "secret-delete-truncated"
+			Expect(validator.ValidateDelete(ctx, oldObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for deleting a consumed secret")
+		})
+
+		It("Should delete a secret with truncated consumers referenced by an unrelated pod", func() {
+			unrelated := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unrelated",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "unrelated",
+							Image: "busybox:latest",
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "secret-delete-unrelated"},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, unrelated)).To(Succeed())
+			unrelated.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, unrelated)).To(Succeed())
+			oldObj.Name = "secret-delete-unrelated"
+			Expect(validator.ValidateDelete(ctx, oldObj)).To(BeNil(),
+				"Expected validation to delete the secret")
+			Expect(k8sClient.Delete(ctx, unrelated)).To(Succeed())
+		})
+
+		It("Should delete once no consumer remains", func() {
+			Expect(k8sClient.Delete(ctx, consumer)).To(Succeed())
+			Expect(validator.ValidateDelete(ctx, oldObj)).To(BeNil(),
+				"Expected validation to delete the secret")
+		})
+
+		It("Should delete when the CR disables deletion protection", func() {
+			deleteList := &batchv1.ImmutableImages{}
+			deleteLookupKey := types.NamespacedName{Name: "imagelist-delete", Namespace: "default"}
+			Expect(k8sClient.Get(ctx, deleteLookupKey, deleteList)).To(Succeed())
+			deleteList.Spec.DeletionProtection = batchv1.DeletionProtectionDisabled
+			Expect(k8sClient.Update(ctx, deleteList)).To(Succeed())
+			Expect(validator.ValidateDelete(ctx, oldObj)).To(BeNil(),
+				"Expected validation to delete the secret")
+		})
+
+		It("Should delete an unlocked secret", func() {
+			oldObj.Name = "secret-1"
+			Expect(validator.ValidateDelete(ctx, oldObj)).To(BeNil(),
+				"Expected validation to delete the secret")
+		})
+	})
+	Context("When recreating a locked Secret", func() {
+		BeforeEach(func() {
+			ctx := context.Background()
+			pinnedList := &batchv1.ImmutableImages{}
+			pinnedLookupKey := types.NamespacedName{
+				Name:      "imagelist-pinned",
+				Namespace: "default",
+			}
+			err := k8sClient.Get(ctx, pinnedLookupKey, pinnedList)
+			if err != nil && errors.IsNotFound(err) {
+				pinnedList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-pinned",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pinnedList)).To(Succeed())
+				pinnedList.Status.LockStatus = batchv1.LockStatus{
+					ImmutableSecrets: []batchv1.NamespacedName{
+						{Namespace: "default", Name: "secret-pinned"},
+					},
+					LockedSecrets: []batchv1.SecretLock{
+						{
+							Namespace: "default",
+							Name:      "secret-pinned",
+							AllKeys:   true,
+							Consumers: []batchv1.SecretConsumer{
+								{PodName: "consumer-pinned", Container: "consumer", Image: "alpine:latest"},
+							},
+							ContentHash: fingerprint.Data(map[string][]byte{"password.txt": []byte("oldpass")}),
+						},
+					},
+					ImmutablePullSecrets: []batchv1.NamespacedName{
+						{Namespace: "default", Name: "pull-pinned"},
+					},
+					LockedPullSecrets: []batchv1.SecretLock{
+						{
+							Namespace: "default",
+							Name:      "pull-pinned",
+							AllKeys:   true,
+							Consumers: []batchv1.SecretConsumer{
+								{PodName: "consumer-pinned", Container: "consumer", Image: "alpine:latest",
+									Reference: batchv1.ReferenceKindImagePullSecret},
+							},
+							ContentHash: fingerprint.Data(map[string][]byte{"password.txt": []byte("oldpass")}),
+						},
+					},
+				}
+				Expect(k8sClient.Status().Update(ctx, pinnedList)).To(Succeed())
+			}
+			oldObj.Name = "secret-pinned"
+		})
+
+		It("Should create the secret with the pinned content", func() {
+			Expect(validator.ValidateCreate(ctx, oldObj)).To(BeNil(),
+				"Expected validation to recreate the secret as it was")
+		})
+
+		It("Should fail for the secret with other content", func() {
+			oldObj.StringData["password.txt"] = "recreated"
+			Expect(validator.ValidateCreate(ctx, oldObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for recreating the secret with other content")
+		})
+
+		It("Should fail for a pull secret with other content", func() {
+			oldObj.Name = "pull-pinned"
+			oldObj.StringData["password.txt"] = "recreated"
+			Expect(validator.ValidateCreate(ctx, oldObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for recreating the pull secret with other content")
+		})
+
+		It("Should create a secret that is not locked", func() {
+			oldObj.Name = "secret-unpinned"
+			oldObj.StringData["password.txt"] = "recreated"
+			Expect(validator.ValidateCreate(ctx, oldObj)).To(BeNil(),
+				"Expected validation to create the secret")
+		})
+	})
+	Context("When a break-glass requester updates a locked Secret", func() {
+		var recorder *record.FakeRecorder
+
+		requestBy := func(user authenticationv1.UserInfo) context.Context {
+			return admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					UserInfo:  user,
+				},
+			})
+		}
+		dryRunBy := func(user authenticationv1.UserInfo) context.Context {
+			dryRun := true
+			return admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					UserInfo:  user,
+					DryRun:    &dryRun,
+				},
+			})
+		}
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			breakGlassList := &batchv1.ImmutableImages{}
+			breakGlassLookupKey := types.NamespacedName{
+				Name:      "imagelist-breakglass",
+				Namespace: "default",
+			}
+			err := k8sClient.Get(ctx, breakGlassLookupKey, breakGlassList)
+			if err != nil && errors.IsNotFound(err) {
+				breakGlassList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-breakglass",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						BreakGlass: &batchv1.BreakGlass{
+							Users:  []string{"sre@example.com"},
+							Groups: []string{"sre-oncall"},
+							ServiceAccounts: []batchv1.ServiceAccountReference{
+								{Namespace: "ops", Name: "rotator"},
+							},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, breakGlassList)).To(Succeed())
+				breakGlassList.Status.ImmutableSecrets = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "secret-breakglass"},
+				}
+				breakGlassList.Status.LockedSecrets = []batchv1.SecretLock{
+					{
+						Namespace:   "default",
+						Name:        "secret-breakglass",
+						AllKeys:     true,
+						ContentHash: fingerprint.Data(map[string][]byte{"password.txt": []byte("oldpass")}),
+					},
+				}
+				Expect(k8sClient.Status().Update(ctx, breakGlassList)).To(Succeed())
+			}
+			recorder = record.NewFakeRecorder(10)
+			validator.recorder = recorder
+			oldObj.Name = "secret-breakglass"
+			newObj.Name = "secret-breakglass"
+			newObj.StringData["password.txt"] = "rotated"
+		})
+
+		It("Should allow a listed user and record the exemption", func() {
+			Expect(validator.ValidateUpdate(requestBy(authenticationv1.UserInfo{Username: "sre@example.com"}),
+				oldObj, newObj)).To(BeNil(), "Expected validation to exempt the listed user")
+			Expect(recorder.Events).To(HaveLen(2), "Expected an Event on the secret and on the CR")
+			Expect(<-recorder.Events).To(ContainSubstring("BreakGlass"))
+		})
+
+		It("Should pin the rotated content of the secret", func() {
+			Expect(validator.ValidateUpdate(requestBy(authenticationv1.UserInfo{Username: "sre@example.com"}),
+				oldObj, newObj)).To(BeNil(), "Expected validation to exempt the listed user")
+			breakGlassList := &batchv1.ImmutableImages{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "imagelist-breakglass", Namespace: "default"},
+				breakGlassList)).To(Succeed())
+			Expect(breakGlassList.Status.LockedSecrets[0].ContentHash).To(
+				Equal(fingerprint.Data(map[string][]byte{"password.txt": []byte("rotated")})))
+		})
+
+		It("Should allow a listed user without recording a dry run", func() {
+			Expect(validator.ValidateUpdate(dryRunBy(authenticationv1.UserInfo{Username: "sre@example.com"}),
+				oldObj, newObj)).To(BeNil(), "Expected validation to exempt the listed user")
+			Expect(recorder.Events).To(BeEmpty(), "Expected no Event for a dry run")
+		})
+
+		It("Should allow a member of a listed group", func() {
+			Expect(validator.ValidateUpdate(requestBy(authenticationv1.UserInfo{
+				Username: "jane@example.com",
+				Groups:   []string{"system:authenticated", "sre-oncall"},
+			}), oldObj, newObj)).To(BeNil(), "Expected validation to exempt the listed group")
+		})
+
+		It("Should allow a listed service account", func() {
+			Expect(validator.ValidateUpdate(requestBy(authenticationv1.UserInfo{
+				Username: "system:serviceaccount:ops:rotator",
+			}), oldObj, newObj)).To(BeNil(), "Expected validation to exempt the listed service account")
+		})
+
+		It("Should fail for any other requester", func() {
+			Expect(validator.ValidateUpdate(requestBy(authenticationv1.UserInfo{
+				Username: "system:serviceaccount:default:rotator",
+			}), oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for a requester that is not listed")
+			Expect(recorder.Events).To(BeEmpty())
+		})
+	})
+	Context("When a break-glass requester updates a Secret also locked by a CR without breakGlass", func() {
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			for _, name := range []string{"imagelist-shared-breakglass", "imagelist-shared-locked"} {
+				sharedList := &batchv1.ImmutableImages{}
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, sharedList)
+				if err == nil || !errors.IsNotFound(err) {
+					continue
+				}
+				sharedList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+					},
+				}
+				if name == "imagelist-shared-breakglass" {
+					sharedList.Spec.BreakGlass = &batchv1.BreakGlass{Users: []string{"sre@example.com"}}
+				}
+				Expect(k8sClient.Create(ctx, sharedList)).To(Succeed())
+				sharedList.Status.ImmutableSecrets = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "secret-shared"},
+				}
+				Expect(k8sClient.Status().Update(ctx, sharedList)).To(Succeed())
+			}
+			recorder = record.NewFakeRecorder(10)
+			validator.recorder = recorder
+			oldObj.Name = "secret-shared"
+			newObj.Name = "secret-shared"
+		})
+
+		It("Should fail without recording an exemption", func() {
+			ctx := admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					UserInfo:  authenticationv1.UserInfo{Username: "sre@example.com"},
+				},
+			})
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for the CR without breakGlass")
+			Expect(recorder.Events).To(BeEmpty(), "Expected no Event for a denied change")
+		})
+	})
+	Context("When updating a Secret locked in Audit mode and by a CR in Enforce mode", func() {
+		auditLookupKey := types.NamespacedName{
+			Name:      "imagelist-shared-audit",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			for _, name := range []string{"imagelist-shared-audit", "imagelist-shared-enforced"} {
+				sharedList := &batchv1.ImmutableImages{}
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, sharedList)
+				if err == nil || !errors.IsNotFound(err) {
+					continue
+				}
+				sharedList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+					},
+				}
+				if name == auditLookupKey.Name {
+					sharedList.Spec.EnforcementMode = batchv1.EnforcementModeAudit
+				}
+				Expect(k8sClient.Create(ctx, sharedList)).To(Succeed())
+				sharedList.Status.ImmutableSecrets = []batchv1.NamespacedName{
+					{Namespace: "default", Name: "secret-shared-audit"},
+				}
+				Expect(k8sClient.Status().Update(ctx, sharedList)).To(Succeed())
+			}
+			oldObj.Name = "secret-shared-audit"
+			newObj.Name = "secret-shared-audit"
+		})
+
+		It("Should fail without recording an audit violation", func() {
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).Error().To(HaveOccurred(),
+				"Expected validation to fail for the CR in Enforce mode")
+			auditList := &batchv1.ImmutableImages{}
+			Expect(k8sClient.Get(ctx, auditLookupKey, auditList)).To(Succeed())
+			Expect(auditList.Status.AuditViolations).To(BeEmpty())
+		})
+	})
+	Context("When updating a locked Secret in Warn or Audit mode", func() {
+		modesLookupKey := types.NamespacedName{
+			Name:      "imagelist-modes",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			ctx := context.Background()
+			modesList := &batchv1.ImmutableImages{}
+			err := k8sClient.Get(ctx, modesLookupKey, modesList)
+			if err != nil && errors.IsNotFound(err) {
+				modesList = &batchv1.ImmutableImages{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "imagelist-modes",
+						Namespace: "default",
+					},
+					Spec: batchv1.ImmutableImagesSpec{
+						Images: []string{
+							"alpine:latest",
+						},
+						EnforcementMode: batchv1.EnforcementModeWarn,
+					},
+				}
+				Expect(k8sClient.Create(ctx, modesList)).To(Succeed())
+				modesList.Status.LockStatus = batchv1.LockStatus{
+					ImmutableSecrets: []batchv1.NamespacedName{
+						{Namespace: "default", Name: "secret-warn"},
+						{Namespace: "default", Name: "secret-audit"},
+					},
+					LockedSecrets: []batchv1.SecretLock{
+						{Namespace: "default", Name: "secret-warn", AllKeys: true, EnforcementMode: batchv1.EnforcementModeWarn},
+						{Namespace: "default", Name: "secret-audit", AllKeys: true, EnforcementMode: batchv1.EnforcementModeAudit},
+					},
+				}
+				Expect(k8sClient.Status().Update(ctx, modesList)).To(Succeed())
+			}
+			newObj.StringData["password.txt"] = "passupdate"
+		})
+
+		It("Should allow the update with a warning in Warn mode", func() {
+			oldObj.Name = "secret-warn"
+			newObj.Name = "secret-warn"
+			warnings, err := validator.ValidateUpdate(ctx, oldObj, newObj)
+			Expect(err).NotTo(HaveOccurred(), "Expected validation to allow the update")
+			Expect(warnings).To(ConsistOf(ContainSubstring("default/secret-warn")))
+		})
+
+		It("Should allow the update silently and record it in Audit mode", func() {
+			oldObj.Name = "secret-audit"
+			newObj.Name = "secret-audit"
+			Expect(validator.ValidateUpdate(ctx, oldObj, newObj)).To(BeEmpty(),
+				"Expected validation to allow the update without warnings")
+
+			modesList := &batchv1.ImmutableImages{}
+			Expect(k8sClient.Get(ctx, modesLookupKey, modesList)).To(Succeed())
+			Expect(modesList.Status.AuditViolations).To(ContainElement(And(
+				HaveField("Kind", "Secret"),
+				HaveField("Name", "secret-audit"),
+			)))
+		})
+	})
 })
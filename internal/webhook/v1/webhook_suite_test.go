@@ -34,6 +34,7 @@ import (
 
 	// +kubebuilder:scaffold:imports
 	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	batchv2 "github.com/brongulus/secret-controller/api/v2"
 	apimachineryruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -95,6 +96,9 @@ var _ = BeforeSuite(func() {
 	err = batchv1.AddToScheme(scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = batchv2.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	err = corev1.AddToScheme(scheme)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -124,6 +128,15 @@ var _ = BeforeSuite(func() {
 	err = SetupSecretWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = SetupConfigMapWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupImmutableImagesWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupClusterImmutableImagesWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:webhook
 
 	go func() {
@@ -0,0 +1,254 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package extractor finds the secrets consumed by the containers of a pod.
+// Controllers built on secret-controller can register extractors of their own
+// for references the built-in ones do not know about, e.g. annotations of a
+// secrets injector.
+package extractor
+
+import (
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SecretReference is a secret consumed by a container of a pod.
+type SecretReference struct {
+	// SecretName is the name of the referenced secret in the namespace of the pod.
+	SecretName string
+	// Container is the name of the consuming container.
+	Container string
+	// ContainerKind is the list of the pod spec the container was declared in.
+	ContainerKind batchv1.ContainerKind
+	// Source is the way the container consumes the secret.
+	Source batchv1.ReferenceKind
+	// Key is the consumed key of the secret, empty when every key is consumed.
+	Key string
+	// Volume is the name of the pod volume of Volume and Projected references.
+	Volume string
+	// MountPath is where the container mounts the volume.
+	MountPath string
+	// ReadOnly is set when the container mounts the volume read-only.
+	ReadOnly bool
+}
+
+// SecretReferenceExtractor finds the secrets consumed by the containers of a pod.
+// Extractors only report references, whether a reference locks its secret is
+// decided by the reconciler from the image of the referencing container.
+type SecretReferenceExtractor interface {
+	ExtractSecretReferences(pod *corev1.Pod) []SecretReference
+}
+
+// Func is an adapter to use a plain function as a SecretReferenceExtractor.
+type Func func(pod *corev1.Pod) []SecretReference
+
+// ExtractSecretReferences calls f(pod).
+func (f Func) ExtractSecretReferences(pod *corev1.Pod) []SecretReference {
+	return f(pod)
+}
+
+// Defaults returns the built-in extractors, used when the reconcilers are not
+// configured with extractors of their own.
+func Defaults() []SecretReferenceExtractor {
+	return []SecretReferenceExtractor{
+		VolumeExtractor{},
+		ProjectedVolumeExtractor{},
+		EnvExtractor{},
+		EnvFromExtractor{},
+	}
+}
+
+// Container is a container of a pod along with the list it was declared in.
+type Container struct {
+	*corev1.Container
+	Kind batchv1.ContainerKind
+	// ImageID is the image the runtime resolved the container to, empty until
+	// the container status reports it.
+	ImageID string
+}
+
+// Containers returns the regular, init and ephemeral containers of the pod.
+// Ephemeral containers share their fields with regular containers, so they are
+// viewed through the same type.
+func Containers(pod *corev1.Pod) []Container {
+	imageIDs := map[string]string{}
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses, pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, status := range statuses {
+			imageIDs[status.Name] = status.ImageID
+		}
+	}
+
+	containers := make([]Container, 0,
+		len(pod.Spec.Containers)+len(pod.Spec.InitContainers)+len(pod.Spec.EphemeralContainers))
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		containers = append(containers, Container{container, batchv1.ContainerKindContainer, imageIDs[container.Name]})
+	}
+	for i := range pod.Spec.InitContainers {
+		container := &pod.Spec.InitContainers[i]
+		containers = append(containers, Container{container, batchv1.ContainerKindInitContainer, imageIDs[container.Name]})
+	}
+	for i := range pod.Spec.EphemeralContainers {
+		container := (*corev1.Container)(&pod.Spec.EphemeralContainers[i].EphemeralContainerCommon)
+		containers = append(containers, Container{container, batchv1.ContainerKindEphemeralContainer, imageIDs[container.Name]})
+	}
+	return containers
+}
+
+// VolumeMounts returns the mounts of the named pod volume by the container.
+func VolumeMounts(container Container, volumeName string) []corev1.VolumeMount {
+	var mounts []corev1.VolumeMount
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == volumeName {
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// mountReference completes the reference with the volume and mount it is
+// consumed through.
+func mountReference(ref SecretReference, mount corev1.VolumeMount) SecretReference {
+	ref.Volume = mount.Name
+	ref.MountPath = mount.MountPath
+	ref.ReadOnly = mount.ReadOnly
+	return ref
+}
+
+// VolumeExtractor reports secret volumes for every container mounting them.
+// Ref: https://kubernetes.io/docs/tasks/inject-data-application/distribute-credentials-secure/
+type VolumeExtractor struct{}
+
+// ExtractSecretReferences implements SecretReferenceExtractor.
+func (VolumeExtractor) ExtractSecretReferences(pod *corev1.Pod) []SecretReference {
+	var refs []SecretReference
+	containers := Containers(pod)
+	// pod.Volumes.Secret.SecretName
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Secret == nil {
+			continue
+		}
+		for _, container := range containers {
+			for _, mount := range VolumeMounts(container, volume.Name) {
+				refs = append(refs, keyReferences(mountReference(SecretReference{
+					SecretName:    volume.Secret.SecretName,
+					Container:     container.Name,
+					ContainerKind: container.Kind,
+					Source:        batchv1.ReferenceKindVolume,
+				}, mount), volume.Secret.Items)...)
+			}
+		}
+	}
+	return refs
+}
+
+// ProjectedVolumeExtractor reports the secret sources of projected volumes for
+// every container mounting them.
+type ProjectedVolumeExtractor struct{}
+
+// ExtractSecretReferences implements SecretReferenceExtractor.
+func (ProjectedVolumeExtractor) ExtractSecretReferences(pod *corev1.Pod) []SecretReference {
+	var refs []SecretReference
+	containers := Containers(pod)
+	// pod.Volumes.Projected.Sources.Secret.Name
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Projected == nil {
+			continue
+		}
+		for _, container := range containers {
+			for _, mount := range VolumeMounts(container, volume.Name) {
+				for _, source := range volume.Projected.Sources {
+					if source.Secret == nil {
+						continue
+					}
+					refs = append(refs, keyReferences(mountReference(SecretReference{
+						SecretName:    source.Secret.Name,
+						Container:     container.Name,
+						ContainerKind: container.Kind,
+						Source:        batchv1.ReferenceKindProjected,
+					}, mount), source.Secret.Items)...)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// keyReferences expands a volume reference into one reference per projected
+// item, a volume without items consumes every key of the secret.
+func keyReferences(ref SecretReference, items []corev1.KeyToPath) []SecretReference {
+	if len(items) == 0 {
+		return []SecretReference{ref}
+	}
+	refs := make([]SecretReference, 0, len(items))
+	for _, item := range items {
+		ref.Key = item.Key
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// EnvExtractor reports secrets consumed through env.valueFrom.secretKeyRef.
+// Ref: https://stackoverflow.com/questions/46406596/how-to-identify-unused-secrets-in-kubernetes
+type EnvExtractor struct{}
+
+// ExtractSecretReferences implements SecretReferenceExtractor.
+func (EnvExtractor) ExtractSecretReferences(pod *corev1.Pod) []SecretReference {
+	var refs []SecretReference
+	// pod.Containers.Env.ValueFrom.SecretKeyRef.Name
+	for _, container := range Containers(pod) {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+				continue
+			}
+			refs = append(refs, SecretReference{
+				SecretName:    env.ValueFrom.SecretKeyRef.Name,
+				Container:     container.Name,
+				ContainerKind: container.Kind,
+				Source:        batchv1.ReferenceKindEnv,
+				Key:           env.ValueFrom.SecretKeyRef.Key,
+			})
+		}
+	}
+	return refs
+}
+
+// EnvFromExtractor reports secrets consumed through envFrom.secretRef.
+type EnvFromExtractor struct{}
+
+// ExtractSecretReferences implements SecretReferenceExtractor.
+func (EnvFromExtractor) ExtractSecretReferences(pod *corev1.Pod) []SecretReference {
+	var refs []SecretReference
+	// pod.Containers.EnvFrom.SecretRef.Name
+	// Use envFrom to define all of the Secret's data as container environment variables.
+	// The key from the Secret becomes the environment variable name in the Pod.
+	for _, container := range Containers(pod) {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef == nil {
+				continue
+			}
+			refs = append(refs, SecretReference{
+				SecretName:    envFrom.SecretRef.Name,
+				Container:     container.Name,
+				ContainerKind: container.Kind,
+				Source:        batchv1.ReferenceKindEnvFrom,
+			})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extractor
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "github.com/brongulus/secret-controller/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Secret reference extractors", func() {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "credentials",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "volume-secret",
+							Items:      []corev1.KeyToPath{{Key: "tls.crt", Path: "tls.crt"}},
+						},
+					},
+				},
+			},
+			InitContainers: []corev1.Container{
+				{
+					Name:  "init",
+					Image: "alpine:latest",
+					EnvFrom: []corev1.EnvFromSource{
+						{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "envfrom-secret"},
+							},
+						},
+					},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:  "app",
+					Image: "nginx:0.3",
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "credentials", MountPath: "/etc/credentials"},
+					},
+					Env: []corev1.EnvVar{
+						{
+							Name: "PASSWORD",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									Key:                  "password",
+									LocalObjectReference: corev1.LocalObjectReference{Name: "env-secret"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	It("should report the consumed keys of secret volumes", func() {
+		Expect(VolumeExtractor{}.ExtractSecretReferences(pod)).To(ConsistOf(SecretReference{
+			SecretName:    "volume-secret",
+			Container:     "app",
+			ContainerKind: batchv1.ContainerKindContainer,
+			Source:        batchv1.ReferenceKindVolume,
+			Key:           "tls.crt",
+			Volume:        "credentials",
+			MountPath:     "/etc/credentials",
+		}))
+	})
+
+	It("should report env and envFrom references", func() {
+		Expect(EnvExtractor{}.ExtractSecretReferences(pod)).To(ConsistOf(SecretReference{
+			SecretName:    "env-secret",
+			Container:     "app",
+			ContainerKind: batchv1.ContainerKindContainer,
+			Source:        batchv1.ReferenceKindEnv,
+			Key:           "password",
+		}))
+		Expect(EnvFromExtractor{}.ExtractSecretReferences(pod)).To(ConsistOf(SecretReference{
+			SecretName:    "envfrom-secret",
+			Container:     "init",
+			ContainerKind: batchv1.ContainerKindInitContainer,
+			Source:        batchv1.ReferenceKindEnvFrom,
+		}))
+	})
+
+	It("should report the references of ephemeral containers", func() {
+		debugged := pod.DeepCopy()
+		debugged.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+			{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name:  "debugger",
+					Image: "busybox:latest",
+					EnvFrom: []corev1.EnvFromSource{
+						{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "debug-secret"},
+							},
+						},
+					},
+				},
+			},
+		}
+		Expect(EnvFromExtractor{}.ExtractSecretReferences(debugged)).To(ContainElement(SecretReference{
+			SecretName:    "debug-secret",
+			Container:     "debugger",
+			ContainerKind: batchv1.ContainerKindEphemeralContainer,
+			Source:        batchv1.ReferenceKindEnvFrom,
+		}))
+	})
+
+	It("should adapt plain functions to extractors", func() {
+		var extractor SecretReferenceExtractor = Func(func(pod *corev1.Pod) []SecretReference {
+			return []SecretReference{{SecretName: pod.Annotations["vault.internal/secret"], Container: "app"}}
+		})
+		annotated := pod.DeepCopy()
+		annotated.Annotations = map[string]string{"vault.internal/secret": "annotated-secret"}
+		Expect(extractor.ExtractSecretReferences(annotated)).To(ConsistOf(
+			SecretReference{SecretName: "annotated-secret", Container: "app"}))
+	})
+})
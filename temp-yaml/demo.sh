@@ -30,7 +30,7 @@ set -x
 { add_separator; } 2>/dev/null
 
 kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-        -o jsonpath='{.spec.imageSecretMap}'
+        -o jsonpath='{.status.imageSecretMap}'
 { set +x; } 2>/dev/null
 echo 
 set -x
@@ -48,7 +48,7 @@ echo
 set -x
 
 kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-        -o jsonpath='{.spec.imageSecretMap}'
+        -o jsonpath='{.status.imageSecretMap}'
 { set +x; } 2>/dev/null
 echo 
 
@@ -61,7 +61,7 @@ echo "Waiting for the reconciler to run..."
 sleep 4
 set -x
 kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-        -o jsonpath='{.spec.imageSecretMap}'
+        -o jsonpath='{.status.imageSecretMap}'
 { set +x; } 2>/dev/null
 echo 
 
@@ -79,10 +79,10 @@ read -rp "Update custom resource?(y/n) [Edit cr.yaml]" yn
 case $yn in 
 	  y ) echo "Check which secrets added to imagemap";
         kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-                -o jsonpath='{.spec.imageSecretMap}';
+                -o jsonpath='{.status.imageSecretMap}';
         kubectl patch immutableimages.batch.github.com immutable-secret-image-list \
                 --type='json' \
-                -p '[{"op": "remove", "path": "/spec/imageSecretMap/alpine:latest", "value": []}]';
+                -p '[{"op": "remove", "path": "/spec/images/0"}]';
         echo Updated;;
           # kubectl delete immutableimages.batch.github.com immutable-secret-image-list;;
 	  n ) echo Not updating CR...;;
@@ -96,7 +96,7 @@ esac
 echo "Now that the immutableimages list doesnt having alpine, we can edit the secret"
 set -x
 kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-        -o jsonpath='{.spec.imageSecretMap}';
+        -o jsonpath='{.status.imageSecretMap}';
 { set +x; } 2>/dev/null
 echo 
 set -x
@@ -117,7 +117,7 @@ case $yn in
         echo "The secret-added-later should not be in CR now";
         set -x;
         kubectl get immutableimages.batch.github.com immutable-secret-image-list \
-                -o jsonpath='{.spec.imageSecretMap}';
+                -o jsonpath='{.status.imageSecretMap}';
         { set +x; } 2>/dev/null;
         echo 
         echo "The edit to secret-added-later is successful";